@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/zhengjingcheng/zjcgo"
 	"github.com/zhengjingcheng/zjcgo/config"
+	"github.com/zhengjingcheng/zjcgo/csrf"
 	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"github.com/zhengjingcheng/zjcgo/secure"
+	"github.com/zhengjingcheng/zjcgo/sessions"
+	"github.com/zhengjingcheng/zjcgo/storage"
 	"github.com/zhengjingcheng/zjcgo/token"
 	"github.com/zhengjingcheng/zjcgo/zjcpool"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -36,8 +44,23 @@ func main() {
 	}
 	auth.Users["zjc"] = "123456"
 	engine.Use(auth.BasicAuth)
+	//安全响应头，配置在app.toml的[secure]里
+	engine.Use(secure.New(secure.FromConfig()))
+
+	//签名cookie的key，轮换时把新key放在前面
+	if err := engine.SetCookieSigningKeys([]byte("zjcgo-blog-cookie-key")); err != nil {
+		log.Fatal(err)
+	}
+	//运行时改日志级别，组在BasicAuth之后创建，需要认证
+	admin := engine.Group("admin")
+	admin.LogLevel(engine.Logger)
+	//app.toml改了以后[log]里的级别自动生效
+	stopWatch := config.Watch(5 * time.Second)
+	engine.OnShutdown(stopWatch)
 
 	g := engine.Group("user") //将路由组的名字加进去，返回user路由组
+	//登录状态保存在服务端会话里
+	g.Use(zjcgo.Sessions(sessions.NewMemoryStore(time.Minute), nil))
 
 	g.Use(func(next zjcgo.HandlerFunc) zjcgo.HandlerFunc {
 		return func(ctx *zjcgo.Context) {
@@ -80,17 +103,24 @@ func main() {
 		}
 	})
 
+	//csrfToken、cspNonce模板函数要在加载模板之前注册
+	engine.SetFuncMap(csrf.FuncMap())
+	engine.SetFuncMap(secure.FuncMap())
 	engine.LoadTemplate("tpl/*.html")
+	csrfProtect := csrf.New(csrf.Config{Keys: [][]byte{[]byte("zjcgo-blog-csrf-key")}})
 
 	g.Get("/template", func(ctx *zjcgo.Context) {
 		user := &User{
-			Name: "ZJC",
+			Name: "未登录",
+		}
+		if name, ok := ctx.Session().Get("name").(string); ok {
+			user.Name = name
 		}
 		err := ctx.Template("login.html", user)
 		if err != nil {
 			log.Panic(err)
 		}
-	})
+	}, csrfProtect)
 
 	g.Get("/json", func(ctx *zjcgo.Context) {
 		user := &User{
@@ -142,10 +172,16 @@ func main() {
 	})
 
 	//测试提交表单参数
+	g.Get("/addForm", func(ctx *zjcgo.Context) {
+		err := ctx.Template("add.html", nil)
+		if err != nil {
+			log.Println(err)
+		}
+	}, csrfProtect)
 	g.Post("/add", func(ctx *zjcgo.Context) {
 		name, _ := ctx.GetPostFormArray("name")
 		fmt.Println(name)
-	})
+	}, csrfProtect)
 	//测试提交map参数
 	g.Post("/add1", func(ctx *zjcgo.Context) {
 		name, _ := ctx.GetPostFormMap("user")
@@ -154,6 +190,12 @@ func main() {
 			return
 		}
 	})
+	//上传的文件交给storage保存，内容相同的只存一份
+	store, err := storage.NewLocal("./upload", "", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g.ServeStorage("/files", store, nil)
 	//测试提取文件
 	g.Post("/add2", func(ctx *zjcgo.Context) {
 		name, _ := ctx.GetPostFormMap("user")
@@ -163,12 +205,13 @@ func main() {
 		}
 		files := ctx.FormFiles("file")
 		for _, file := range files {
-			err := ctx.SaveUploadedFile(file, "./upload/"+file.Filename)
+			_, err := storage.PutFile(ctx.R.Context(), store, "", file, "image/*")
 			if err != nil {
+				log.Println(err)
 				return
 			}
 		}
-	})
+	}, zjcgo.BodyLimit(20<<20))
 
 	//json
 	g.Post("/jsonParm", func(ctx *zjcgo.Context) {
@@ -203,8 +246,7 @@ func main() {
 		}
 	})
 	engine.Logger.Formatter = &zjcLog.TextFormatter{}
-	engine.Logger.SetLogPath("./log")
-	defer engine.Logger.CloseWriter()
+	//日志目录在conf/app.toml的[log] path里配置，Default()已经设置过了，退出时Shutdown会关掉
 	g.Post("/xmlParam1", func(ctx *zjcgo.Context) {
 		user := &User{}
 		_ = ctx.BindXML(user)
@@ -216,12 +258,15 @@ func main() {
 
 		ctx.Logger.Info("我是info日志")
 
+		ctx.Logger.Warnf("我是warn日志 %s", ctx.R.URL.Path)
+
 		ctx.Logger.Error("我是error日志")
 
 		ctx.JSON(http.StatusOK, user)
 	})
 
 	p, _ := zjcpool.NewPool(5)
+	engine.OnShutdown(p.Release)
 	g.Post("/pool", func(ctx *zjcgo.Context) {
 		currentTime := time.Now().UnixMilli()
 		var wg sync.WaitGroup
@@ -260,6 +305,7 @@ func main() {
 		jwt := &token.JwtHandler{}
 		jwt.Key = []byte("123456")
 		jwt.SendCookie = true
+		jwt.CookieMode = token.CookieSigned
 		jwt.TimeOut = 10 * time.Minute
 		jwt.RefreshTimeOut = 20 * time.Minute
 		jwt.Authenticator = func(ctx *zjcgo.Context) (map[string]any, error) {
@@ -273,7 +319,33 @@ func main() {
 			ctx.JSON(http.StatusOK, err.Error())
 			return
 		}
+		//登录成功换一个会话id再保存
+		session := ctx.Session()
+		session.Set("userId", 1)
+		session.Set("name", "ZJC")
+		session.RegenerateID()
+		if err := session.Save(); err != nil {
+			log.Println(err)
+		}
 		ctx.JSON(http.StatusOK, token)
 	})
-	engine.Run()
+	//退出会改状态，用post并且校验csrf token，不能被别的站点用链接触发
+	g.Post("/logout", func(ctx *zjcgo.Context) {
+		session := ctx.Session()
+		session.Clear()
+		if err := session.Save(); err != nil {
+			log.Println(err)
+		}
+		ctx.Redirect(http.StatusSeeOther, "/user/template")
+	}, csrfProtect)
+	go engine.Run(":8080")
+	//收到退出信号后等处理中的请求结束，再把异步日志写完
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := engine.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
 }
@@ -3,23 +3,43 @@ package main
 import (
 	"encoding/gob"
 	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/mserror"
 	"github.com/zhengjingcheng/zjcgo/rpc"
 	"github.com/zjc/goodscenter/model"
 	"github.com/zjc/goodscenter/service"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
 	engine := zjcgo.Default()
+	engine.Use(zjcgo.Metrics, zjcgo.Trace, zjcgo.RequestID)
+	engine.ExposeMetrics("/metrics", nil)
+	engine.Health("/healthz")
+	//错误也按Result的结构返回
+	engine.RegisterErrorHandler(zjcgo.EnvelopeErrorHandler(func(e *mserror.Error) any {
+		return &model.Result{Code: e.Code, Msg: e.Message, Data: e.Details}
+	}))
 	group := engine.Group("goods")
-	group.Get("/find", func(ctx *zjcgo.Context) {
+	//商品数据变化不频繁，客户端带ETag来的直接304
+	group.Use(zjcgo.Conditional)
+	//查询结果在服务端缓存10秒，商品修改后调用goodsCache.InvalidateTags("goods")
+	goodsCache := zjcgo.NewResponseCache(zjcgo.ResponseCacheConfig{
+		TTL: 10 * time.Second,
+		Tags: func(ctx *zjcgo.Context) []string {
+			return []string{"goods"}
+		},
+	})
+	//缓存直接包handler，命中缓存也会经过日志、指标这些中间件
+	group.Get("/find", goodsCache.Handle(func(ctx *zjcgo.Context) {
 		goods := &model.Goods{Id: 1000, Name: "zjc"}
 		ctx.JSON(http.StatusOK, &model.Result{Code: 200, Msg: "success", Data: goods})
-	})
+	}))
 	group.Post("/find", func(ctx *zjcgo.Context) {
 		goods := &model.Goods{Id: 1000, Name: "zjc"}
 		ctx.JSON(http.StatusOK, &model.Result{Code: 200, Msg: "success", Data: goods})
+		goodsCache.InvalidateTags("goods")
 	})
 	//grpc方式注册服务
 	tcpServer, err := rpc.NewTcpServer(":9222")
@@ -1,8 +1,9 @@
 package main
 
 import (
-	"context"
 	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/health"
+	"github.com/zhengjingcheng/zjcgo/mserror"
 	"github.com/zhengjingcheng/zjcgo/rpc"
 	"github.com/zjc/ordercenter/service"
 	"log"
@@ -11,6 +12,10 @@ import (
 
 func main() {
 	engine := zjcgo.Default()
+	engine.Use(zjcgo.Metrics, zjcgo.Trace, zjcgo.RequestID)
+	engine.ExposeMetrics("/metrics", nil)
+	engine.Health("/healthz")
+	engine.Health("/readyz", health.Tcp("goods", rpc.DefaultOption))
 	client := rpc.NewHttpClient()
 	client.RegisterHttpService("goods", &service.GoodsService{})
 	group := engine.Group("order")
@@ -19,8 +24,17 @@ func main() {
 		proxy := rpc.NewMsTcpClientProxy(rpc.DefaultOption)
 		params := make([]any, 1)
 		params[0] = int64(1)
-		result, err := proxy.Call(context.Background(), "goods", "Find", params)
-		log.Println(err)
+		result, err := proxy.Call(ctx.R.Context(), "goods", "Find", params)
+		if err != nil {
+			log.Println(err)
+			ctx.Error(mserror.ErrServiceUnavailable.Wrap(err))
+			return
+		}
+		//商品中心返回的业务错误，错误码原样返回给前端
+		if rsp, ok := result.(*rpc.MsRpcResponse); ok && rsp.Err() != nil {
+			ctx.Error(rsp.Err())
+			return
+		}
 		ctx.JSON(http.StatusOK, result)
 	})
 	engine.Run(":9003")
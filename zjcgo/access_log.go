@@ -0,0 +1,168 @@
+package zjcgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zhengjingcheng/zjcgo/config"
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	内置的访问日志格式
+	engine := zjcgo.New()
+	engine.Use(zjcgo.AccessLog(zjcgo.LoggerConfig{Formatter: zjcgo.CombinedLogFormatter, Logger: engine.Logger, SkipPaths: []string{"/healthz"}}))
+	用Default()的话在app.toml里配置
+	[log]
+	access_format="combined" #text(默认) common combined json
+	access_skip=["/healthz"]
+	access_to_logger=true #写到engine.Logger的输出里
+*/
+
+//JSONLogFormatter可选的字段
+const (
+	AccessRequestSize  = "request_size"
+	AccessResponseSize = "response_size"
+	AccessUserAgent    = "user_agent"
+	AccessReferer      = "referer"
+	AccessRequestID    = "request_id"
+	AccessRoute        = "route"
+)
+
+func AccessLog(conf LoggerConfig) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return LoggerWithConfig(conf, next)
+	}
+}
+
+//从[log]里读访问日志的配置
+func accessLogConfig(logger *zjcLog.Logger) LoggerConfig {
+	logConf := config.Section("log")
+	conf := LoggerConfig{SkipPaths: config.StringSlice(logConf, "access_skip", nil)}
+	switch strings.ToLower(config.String(logConf, "access_format", "")) {
+	case "common":
+		conf.Formatter = CommonLogFormatter
+	case "combined":
+		conf.Formatter = CombinedLogFormatter
+	case "json":
+		conf.Formatter = JSONLogFormatter()
+	}
+	if config.Bool(logConf, "access_to_logger", false) {
+		conf.Logger = logger
+	}
+	return conf
+}
+
+const apacheTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+//Apache Common Log Format
+//127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+func CommonLogFormatter(params LogFormatterParams) string {
+	var sb strings.Builder
+	writeCommon(&sb, params)
+	return sb.String()
+}
+
+//Apache Combined Log Format，Common后面加上referer和user agent
+func CombinedLogFormatter(params LogFormatterParams) string {
+	var sb strings.Builder
+	writeCommon(&sb, params)
+	sb.WriteString(" ")
+	sb.WriteString(strconv.Quote(dash(params.Referer)))
+	sb.WriteString(" ")
+	sb.WriteString(strconv.Quote(dash(params.UserAgent)))
+	return sb.String()
+}
+
+func writeCommon(sb *strings.Builder, params LogFormatterParams) {
+	sb.WriteString(dash(params.ClientIP.String()))
+	sb.WriteString(" - ")
+	sb.WriteString(apacheEscape(dash(params.User)))
+	sb.WriteString(" [")
+	sb.WriteString(params.TimeStamp.Format(apacheTimeFormat))
+	sb.WriteString("] \"")
+	sb.WriteString(apacheEscape(params.Method))
+	sb.WriteString(" ")
+	//用编码过的地址，解码后的路径里可能有换行，能伪造出一行日志
+	uri := params.Path
+	if params.Request != nil && params.Request.URL != nil {
+		uri = params.Request.URL.RequestURI()
+	}
+	sb.WriteString(apacheEscape(uri))
+	sb.WriteString(" ")
+	proto := "HTTP/1.1"
+	if params.Request != nil {
+		proto = params.Request.Proto
+	}
+	sb.WriteString(proto)
+	sb.WriteString("\" ")
+	sb.WriteString(strconv.Itoa(params.StatusCode))
+	sb.WriteString(" ")
+	if params.ResponseSize > 0 {
+		sb.WriteString(strconv.Itoa(params.ResponseSize))
+	} else {
+		sb.WriteString("-")
+	}
+}
+
+//和Apache一样，引号和反斜杠前面加\，控制字符和非ASCII字符写成\xhh
+func apacheEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&sb, "\\x%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func dash(s string) string {
+	if s == "" || s == "<nil>" {
+		return "-"
+	}
+	return s
+}
+
+//每行一个json对象，固定有ts、status、method、path、latency_ms、client_ip，fields是可选的字段，不传就全部带上
+func JSONLogFormatter(fields ...string) LoggerFormatter {
+	if len(fields) == 0 {
+		fields = []string{AccessRequestSize, AccessResponseSize, AccessUserAgent, AccessReferer, AccessRequestID, AccessRoute}
+	}
+	return func(params LogFormatterParams) string {
+		entry := map[string]any{
+			"ts":         params.TimeStamp.Format(time.RFC3339Nano),
+			"status":     params.StatusCode,
+			"method":     params.Method,
+			"path":       params.Path,
+			"latency_ms": float64(params.Latency) / float64(time.Millisecond),
+			"client_ip":  params.ClientIP.String(),
+		}
+		for _, field := range fields {
+			switch field {
+			case AccessRequestSize:
+				entry[field] = params.RequestSize
+			case AccessResponseSize:
+				entry[field] = params.ResponseSize
+			case AccessUserAgent:
+				entry[field] = params.UserAgent
+			case AccessReferer:
+				entry[field] = params.Referer
+			case AccessRequestID:
+				entry[field] = params.RequestID
+			case AccessRoute:
+				entry[field] = params.Route
+			}
+		}
+		data, _ := json.Marshal(entry)
+		return string(data)
+	}
+}
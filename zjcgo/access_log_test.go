@@ -0,0 +1,94 @@
+package zjcgo
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApacheLogFormatters(t *testing.T) {
+	ts := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+	params := func(target string, user string) LogFormatterParams {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		return LogFormatterParams{
+			Request:      r,
+			TimeStamp:    ts,
+			StatusCode:   200,
+			ClientIP:     net.ParseIP("127.0.0.1"),
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			ResponseSize: 2326,
+			User:         user,
+			UserAgent:    `curl/8.0 "x"`,
+		}
+	}
+	tests := []struct {
+		name      string
+		formatter LoggerFormatter
+		params    LogFormatterParams
+		want      string
+	}{
+		{"common", CommonLogFormatter, params("/apache_pb.gif", "frank"),
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.1" 200 2326`},
+		{"common forged newline", CommonLogFormatter, params("/a%0A127.0.0.1%20-%20admin", "a\"b\n"),
+			`127.0.0.1 - a\"b\x0a [10/Oct/2000:13:55:36 -0700] "GET /a%0A127.0.0.1%20-%20admin HTTP/1.1" 200 2326`},
+		{"combined", CombinedLogFormatter, params("/p?q=1", ""),
+			`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /p?q=1 HTTP/1.1" 200 2326 "-" "curl/8.0 \"x\""`},
+	}
+	for _, tt := range tests {
+		if got := tt.formatter(tt.params); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+		if strings.Contains(tt.formatter(tt.params), "\n") {
+			t.Errorf("%s: line contains a newline", tt.name)
+		}
+	}
+}
+
+func TestJSONLogFormatterFields(t *testing.T) {
+	p := LogFormatterParams{StatusCode: 200, Method: "GET", Path: "/a", RequestID: "id1", UserAgent: "ua", Route: "/a"}
+	tests := []struct {
+		fields []string
+		want   []string
+		absent []string
+	}{
+		{nil, []string{AccessRequestID, AccessUserAgent, AccessRoute, AccessReferer}, nil},
+		{[]string{AccessRequestID}, []string{AccessRequestID}, []string{AccessUserAgent, AccessRoute}},
+	}
+	for _, tt := range tests {
+		entry := map[string]any{}
+		if err := json.Unmarshal([]byte(JSONLogFormatter(tt.fields...)(p)), &entry); err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range append([]string{"ts", "status", "method", "path", "latency_ms", "client_ip"}, tt.want...) {
+			if _, ok := entry[k]; !ok {
+				t.Errorf("fields %v: missing %s", tt.fields, k)
+			}
+		}
+		for _, k := range tt.absent {
+			if _, ok := entry[k]; ok {
+				t.Errorf("fields %v: unexpected %s", tt.fields, k)
+			}
+		}
+	}
+}
+
+func TestAccessLogSkipPaths(t *testing.T) {
+	var out strings.Builder
+	engine := New()
+	engine.Use(AccessLog(LoggerConfig{Formatter: CommonLogFormatter, Out: &out, SkipPaths: []string{"/api/healthz"}}))
+	g := engine.Group("api")
+	g.Get("/healthz", func(ctx *Context) { ctx.String(http.StatusOK, "ok") })
+	g.Get("/goods", func(ctx *Context) { ctx.String(http.StatusOK, "goods") })
+	for _, path := range []string{"/api/healthz", "/api/goods", "/api/healthz"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"GET /api/goods HTTP/1.1" 200 5`) {
+		t.Fatalf("access log:\n%s", out.String())
+	}
+}
@@ -0,0 +1,368 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"github.com/zhengjingcheng/zjcgo/internal/singleflight"
+	"sync"
+	"time"
+)
+
+/*
+	进程内缓存
+	c := cache.New[int64, *model.Goods](cache.Config[int64, *model.Goods]{MaxEntries: 1000, TTL: time.Minute})
+	goods, err := c.GetOrLoad(id, func(id int64) (*model.Goods, error) {
+		查库
+	})
+*/
+
+//淘汰策略
+type Policy int
+
+const (
+	LRU Policy = iota //最近最少使用
+	LFU               //最不经常使用
+)
+
+//缓存项被移除的原因
+type EvictReason int
+
+const (
+	Expired  EvictReason = iota //过期
+	Capacity                    //超过容量被淘汰
+	Deleted                     //手动删除
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Capacity:
+		return "capacity"
+	case Deleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+type Config[K comparable, V any] struct {
+	Policy          Policy
+	MaxEntries      int                                      //最多缓存的条目，<=0不限制
+	MaxCost         int64                                    //所有值的Cost加起来的上限，<=0不限制
+	Cost            func(value V) int64                      //每个值的大小，默认每个算1
+	TTL             time.Duration                            //默认过期时间，<=0不过期
+	CleanupInterval time.Duration                            //>0时后台定时清理过期的，否则访问到才清理
+	OnEvict         func(key K, value V, reason EvictReason) //被移除时回调，不在锁里执行
+}
+
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Loads     uint64 //GetOrLoad调用loader的次数
+	Evictions uint64 //因为容量淘汰的次数
+	Expired   uint64
+}
+
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	cost    int64
+	expires time.Time
+	elem    *list.Element //LRU用
+	index   int           //LFU堆里的位置
+	freq    uint64
+	tick    uint64 //频率一样时先淘汰更久没访问的
+}
+
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+type Cache[K comparable, V any] struct {
+	conf  Config[K, V]
+	mu    sync.Mutex
+	items map[K]*entry[K, V]
+	ll    *list.List
+	heap  lfuHeap[K, V]
+	tick  uint64
+	cost  int64
+	stats Stats
+	group singleflight.Group[K, V]
+	done  chan struct{}
+	once  sync.Once
+}
+
+func New[K comparable, V any](conf Config[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		conf:  conf,
+		items: make(map[K]*entry[K, V]),
+		ll:    list.New(),
+		done:  make(chan struct{}),
+	}
+	if conf.CleanupInterval > 0 {
+		go c.janitor(conf.CleanupInterval)
+	}
+	return c
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	e, ok := c.items[key]
+	if ok && c.expired(e) {
+		c.remove(e)
+		c.stats.Expired++
+		c.stats.Misses++
+		c.mu.Unlock()
+		c.notify([]evicted[K, V]{{e.key, e.value, Expired}})
+		var zero V
+		return zero, false
+	}
+	if !ok {
+		c.stats.Misses++
+		c.mu.Unlock()
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.touch(e)
+	v := e.value
+	c.mu.Unlock()
+	return v, true
+}
+
+//用默认的TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.conf.TTL)
+}
+
+//ttl<=0不过期
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var cost int64 = 1
+	if c.conf.Cost != nil {
+		cost = c.conf.Cost(value)
+	}
+	c.mu.Lock()
+	if old, ok := c.items[key]; ok {
+		c.remove(old)
+	}
+	//先腾出位置再放进去，LFU新放进去的访问次数最少，后淘汰的话会把自己淘汰掉
+	var out []evicted[K, V]
+	for len(c.items) > 0 && c.overflow(len(c.items)+1, c.cost+cost) {
+		victim := c.victim()
+		c.remove(victim)
+		c.stats.Evictions++
+		out = append(out, evicted[K, V]{victim.key, victim.value, Capacity})
+	}
+	e := &entry[K, V]{key: key, value: value, cost: cost}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	c.items[key] = e
+	c.cost += cost
+	c.tick++
+	e.tick = c.tick
+	if c.conf.Policy == LFU {
+		heap.Push(&c.heap, e)
+	} else {
+		e.elem = c.ll.PushFront(e)
+	}
+	c.mu.Unlock()
+	c.notify(out)
+}
+
+//缓存里没有就调用loader加载，同一个key同时只会调用一次loader，loader出错不缓存
+func (c *Cache[K, V]) GetOrLoad(key K, loader func(key K) (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	v, err, _ := c.group.Do(key, func() (V, error) {
+		c.mu.Lock()
+		c.stats.Loads++
+		c.mu.Unlock()
+		v, err := loader(key)
+		if err != nil {
+			return v, err
+		}
+		c.Set(key, v)
+		return v, nil
+	})
+	return v, err
+}
+
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	e, ok := c.items[key]
+	if ok {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+	if ok {
+		c.notify([]evicted[K, V]{{e.key, e.value, Deleted}})
+	}
+}
+
+//删除所有fn返回true的
+func (c *Cache[K, V]) DeleteFunc(fn func(key K, value V) bool) int {
+	c.mu.Lock()
+	var out []evicted[K, V]
+	for _, e := range c.items {
+		if fn(e.key, e.value) {
+			c.remove(e)
+			out = append(out, evicted[K, V]{e.key, e.value, Deleted})
+		}
+	}
+	c.mu.Unlock()
+	c.notify(out)
+	return len(out)
+}
+
+func (c *Cache[K, V]) Clear() {
+	c.DeleteFunc(func(K, V) bool { return true })
+}
+
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+//停止后台清理
+func (c *Cache[K, V]) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+//清理过期的缓存项
+func (c *Cache[K, V]) DeleteExpired() {
+	c.mu.Lock()
+	var out []evicted[K, V]
+	for _, e := range c.items {
+		if c.expired(e) {
+			c.remove(e)
+			c.stats.Expired++
+			out = append(out, evicted[K, V]{e.key, e.value, Expired})
+		}
+	}
+	c.mu.Unlock()
+	c.notify(out)
+}
+
+func (c *Cache[K, V]) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Cache[K, V]) expired(e *entry[K, V]) bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
+}
+
+func (c *Cache[K, V]) overflow(entries int, cost int64) bool {
+	if c.conf.MaxEntries > 0 && entries > c.conf.MaxEntries {
+		return true
+	}
+	return c.conf.MaxCost > 0 && cost > c.conf.MaxCost
+}
+
+//以下调用方持有锁
+func (c *Cache[K, V]) touch(e *entry[K, V]) {
+	c.tick++
+	e.tick = c.tick
+	if c.conf.Policy == LFU {
+		e.freq++
+		heap.Fix(&c.heap, e.index)
+		return
+	}
+	c.ll.MoveToFront(e.elem)
+}
+
+func (c *Cache[K, V]) victim() *entry[K, V] {
+	if c.conf.Policy == LFU {
+		if len(c.heap) == 0 {
+			return nil
+		}
+		return c.heap[0]
+	}
+	if back := c.ll.Back(); back != nil {
+		return back.Value.(*entry[K, V])
+	}
+	return nil
+}
+
+func (c *Cache[K, V]) remove(e *entry[K, V]) {
+	delete(c.items, e.key)
+	c.cost -= e.cost
+	if c.conf.Policy == LFU {
+		heap.Remove(&c.heap, e.index)
+		return
+	}
+	c.ll.Remove(e.elem)
+}
+
+func (c *Cache[K, V]) notify(out []evicted[K, V]) {
+	if c.conf.OnEvict == nil {
+		return
+	}
+	for _, ev := range out {
+		c.conf.OnEvict(ev.key, ev.value, ev.reason)
+	}
+}
+
+//按访问次数排的小顶堆
+type lfuHeap[K comparable, V any] []*entry[K, V]
+
+func (h lfuHeap[K, V]) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap[K, V]) Less(i, j int) bool {
+	if h[i].freq == h[j].freq {
+		return h[i].tick < h[j].tick
+	}
+	return h[i].freq < h[j].freq
+}
+
+func (h lfuHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
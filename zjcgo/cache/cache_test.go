@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	var evicted []string
+	c := New[string, int](Config[string, int]{
+		MaxEntries: 2,
+		OnEvict: func(key string, value int, reason EvictReason) {
+			evicted = append(evicted, key+":"+reason.String())
+		},
+	})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should be evicted")
+	}
+	if len(evicted) != 1 || evicted[0] != "b:capacity" {
+		t.Fatalf("unexpected evictions %v", evicted)
+	}
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLFU(t *testing.T) {
+	c := New[string, int](Config[string, int]{Policy: LFU, MaxEntries: 2})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should be evicted")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatal("new entry should be kept")
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	c := New[string, string](Config[string, string]{
+		MaxCost: 10,
+		Cost:    func(v string) int64 { return int64(len(v)) },
+	})
+	c.Set("a", "12345")
+	c.Set("b", "12345")
+	c.Set("c", "1")
+	if c.Len() != 2 {
+		t.Fatalf("len %d, want 2", c.Len())
+	}
+}
+
+func TestTTL(t *testing.T) {
+	var reason EvictReason = -1
+	c := New[string, int](Config[string, int]{
+		TTL:     10 * time.Millisecond,
+		OnEvict: func(key string, value int, r EvictReason) { reason = r },
+	})
+	c.Set("a", 1)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired entry returned")
+	}
+	if reason != Expired {
+		t.Fatalf("reason %v, want expired", reason)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := New[int, int](Config[int, int]{})
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(1, func(key int) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(20 * time.Millisecond)
+				return key * 10, nil
+			})
+			if err != nil || v != 10 {
+				t.Errorf("got %d %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+	_, err := c.GetOrLoad(2, func(key int) (int, error) {
+		return 0, errors.New("db down")
+	})
+	if err == nil || c.Len() != 1 {
+		t.Fatal("failed load should not be cached")
+	}
+}
@@ -0,0 +1,144 @@
+package zjcgo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+	条件请求和缓存相关的响应头
+	ctx.SetLastModified(goods.UpdateTime)
+	ctx.CacheControl("public", "max-age=60")
+	if ctx.IsFresh() {
+		ctx.NotModified()
+		return
+	}
+	或者用Conditional中间件，GET/HEAD请求自动给body生成ETag并返回304
+*/
+
+//根据内容生成ETag，weak为true时生成弱ETag W/"..."
+func ETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+//设置ETag，没带引号的会加上
+func (c *Context) SetETag(etag string) {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	c.W.Header().Set("ETag", etag)
+}
+
+func (c *Context) SetLastModified(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	c.W.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+//ctx.CacheControl("no-store") ctx.CacheControl("public", "max-age=60")
+func (c *Context) CacheControl(directives ...string) {
+	c.W.Header().Set("Cache-Control", strings.Join(directives, ", "))
+}
+
+//按请求的If-None-Match和If-Modified-Since判断客户端的缓存是不是还能用
+//要先设置好响应的ETag或者Last-Modified，If-None-Match优先
+func (c *Context) IsFresh() bool {
+	if c.R.Method != http.MethodGet && c.R.Method != http.MethodHead {
+		return false
+	}
+	if strings.Contains(c.R.Header.Get("Cache-Control"), "no-cache") {
+		return false
+	}
+	header := c.W.Header()
+	if inm := c.R.Header.Get("If-None-Match"); inm != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		return etagMatch(inm, etag)
+	}
+	if ims := c.R.Header.Get("If-Modified-Since"); ims != "" {
+		lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+//返回304，去掉body相关的头
+func (c *Context) NotModified() {
+	header := c.W.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Transfer-Encoding")
+	c.W.WriteHeader(http.StatusNotModified)
+	c.StatusCode = http.StatusNotModified
+}
+
+//弱比较，W/"a"和"a"算相同
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type ConditionalConfig struct {
+	Weak bool //生成弱ETag
+}
+
+//GET/HEAD请求的响应先缓存起来，200的响应没有ETag时按body生成，客户端缓存还能用就返回304
+func ConditionalWithConfig(conf ConditionalConfig) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if ctx.R.Method != http.MethodGet && ctx.R.Method != http.MethodHead {
+				next(ctx)
+				return
+			}
+			w := ctx.W
+			bw := newBufferWriter(w)
+			ctx.W = bw
+			next(ctx)
+			ctx.W = w
+			if bw.passthrough || !bw.wroteHeader {
+				return
+			}
+			if bw.status == http.StatusOK {
+				if w.Header().Get("ETag") == "" {
+					w.Header().Set("ETag", ETag(bw.Body(), conf.Weak))
+				}
+				if ctx.IsFresh() {
+					ctx.NotModified()
+					return
+				}
+			}
+			w.WriteHeader(bw.status)
+			_, _ = w.Write(bw.Body())
+		}
+	}
+}
+
+func Conditional(next HandlerFunc) HandlerFunc {
+	return ConditionalWithConfig(ConditionalConfig{})(next)
+}
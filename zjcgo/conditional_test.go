@@ -0,0 +1,100 @@
+package zjcgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalETag(t *testing.T) {
+	engine := New()
+	g := engine.Group("api")
+	g.Use(Conditional)
+	g.Any("/goods", func(ctx *Context) {
+		ctx.String(http.StatusOK, "goods")
+	})
+	g.Get("/missing", func(ctx *Context) {
+		ctx.String(http.StatusNotFound, "missing")
+	})
+	do := func(method, path, inm string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/goods", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "goods" || etag != ETag([]byte("goods"), false) {
+		t.Fatalf("first request: %d %q etag %q", w.Code, w.Body.String(), etag)
+	}
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = do(http.MethodGet, "/api/goods", inm)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+			t.Fatalf("If-None-Match %s: %d %q %v", inm, w.Code, w.Body.String(), w.Header())
+		}
+	}
+	if w = do(http.MethodGet, "/api/goods", `"other"`); w.Code != http.StatusOK || w.Body.String() != "goods" {
+		t.Fatalf("stale etag: %d", w.Code)
+	}
+	//客户端要求不用缓存
+	if w = do(http.MethodGet, "/api/goods", etag, "Cache-Control", "no-cache"); w.Code != http.StatusOK {
+		t.Fatalf("no-cache: %d", w.Code)
+	}
+	//非GET/HEAD和非200的响应原样返回
+	if w = do(http.MethodPost, "/api/goods", etag); w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Fatalf("POST: %d etag %q", w.Code, w.Header().Get("ETag"))
+	}
+	if w = do(http.MethodGet, "/api/missing", "*"); w.Code != http.StatusNotFound || w.Body.String() != "missing" {
+		t.Fatalf("404: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestConditionalLastModified(t *testing.T) {
+	modified := time.Date(2026, 10, 18, 8, 0, 0, 500, time.UTC)
+	engine := New()
+	g := engine.Group("api")
+	g.Get("/goods", func(ctx *Context) {
+		ctx.SetLastModified(modified)
+		ctx.SetETag("v1")
+		ctx.String(http.StatusOK, "goods")
+	}, Conditional)
+	do := func(header, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/goods", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w
+	}
+	//处理函数设置的ETag不会被覆盖
+	if w := do("", ""); w.Header().Get("ETag") != `"v1"` || w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+		t.Fatalf("headers %v", w.Header())
+	}
+	if w := do("If-Modified-Since", modified.Format(http.TimeFormat)); w.Code != http.StatusNotModified {
+		t.Fatalf("same time: %d", w.Code)
+	}
+	if w := do("If-Modified-Since", modified.Add(time.Hour).Format(http.TimeFormat)); w.Code != http.StatusNotModified {
+		t.Fatalf("later time: %d", w.Code)
+	}
+	if w := do("If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat)); w.Code != http.StatusOK || w.Body.String() != "goods" {
+		t.Fatalf("earlier time: %d", w.Code)
+	}
+	//If-None-Match优先
+	r := httptest.NewRequest(http.MethodGet, "/api/goods", nil)
+	r.Header.Set("If-None-Match", `"v0"`)
+	r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("If-None-Match should win: %d", w.Code)
+	}
+}
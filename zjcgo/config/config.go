@@ -5,32 +5,140 @@ import (
 	"github.com/BurntSushi/toml"
 	zjcLog "github.com/zhengjingcheng/zjcgo/log"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 var Conf = &ZjcConfig{
 	logger: zjcLog.Default(),
 }
 
+//Watch在后台Reload时会替换这几个map，运行中读配置用Section
 type ZjcConfig struct {
 	logger *zjcLog.Logger
 	Log    map[string]any
 	Pool   map[string]any
+	Secure map[string]any
 }
 
+//-conf 指定配置文件，注册到flag里是为了程序自己调用flag.Parse时不报错
+var confFlag = flag.String("conf", "conf/app.toml", "app  config file")
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(conf *ZjcConfig)
+	//保护Conf里的map被Reload替换
+	confMu sync.RWMutex
+)
+
 func init() {
 	loadToml()
 }
+
+//init的时候flag还没Parse，这里自己从命令行参数里找-conf，不调用flag.Parse，免得go test之类的参数报错
+func configFile() string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if len(name) == len(arg) || len(arg)-len(name) > 2 {
+			continue
+		}
+		if name == "conf" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "conf=") {
+			return strings.TrimPrefix(name, "conf=")
+		}
+	}
+	return *confFlag
+}
+
 func loadToml() {
 	//如果不指定就用默认的
-	configFile := flag.String("conf", "conf/app.toml", "app  config file")
-	flag.Parse()
-	if _, err := os.Stat(*configFile); err != nil {
+	configFile := configFile()
+	if _, err := os.Stat(configFile); err != nil {
 		Conf.logger.Info("conf/app.toml file not load，because not exist")
 		return
 	}
-	_, err := toml.DecodeFile(*configFile, Conf)
+	_, err := toml.DecodeFile(configFile, Conf)
 	if err != nil {
 		Conf.logger.Info("conf/app.toml decode fail check format")
 		panic(err)
 	}
 }
+
+//按名字取配置段 log、pool、secure，和Reload不会冲突，取出来的map不要修改
+func Section(name string) map[string]any {
+	confMu.RLock()
+	defer confMu.RUnlock()
+	switch name {
+	case "log":
+		return Conf.Log
+	case "pool":
+		return Conf.Pool
+	case "secure":
+		return Conf.Secure
+	}
+	return nil
+}
+
+//配置重新加载以后调用
+func OnReload(fn func(conf *ZjcConfig)) {
+	reloadMu.Lock()
+	reloadHooks = append(reloadHooks, fn)
+	reloadMu.Unlock()
+}
+
+//重新读配置文件，解析失败的话保持原来的配置
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	conf := &ZjcConfig{}
+	if _, err := toml.DecodeFile(configFile(), conf); err != nil {
+		return err
+	}
+	confMu.Lock()
+	Conf.Log = conf.Log
+	Conf.Pool = conf.Pool
+	Conf.Secure = conf.Secure
+	confMu.Unlock()
+	for _, fn := range reloadHooks {
+		fn(Conf)
+	}
+	return nil
+}
+
+//每隔interval检查配置文件有没有改，改了就Reload，返回的函数用来停止
+func Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var modTime time.Time
+		if info, err := os.Stat(configFile()); err == nil {
+			modTime = info.ModTime()
+		}
+		for {
+			select {
+			case <-ticker.C:
+				info, err := os.Stat(configFile())
+				if err != nil || !info.ModTime().After(modTime) {
+					continue
+				}
+				modTime = info.ModTime()
+				if err := Reload(); err != nil {
+					Conf.logger.Error("reload config: " + err.Error())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
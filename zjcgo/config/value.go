@@ -0,0 +1,65 @@
+package config
+
+import "time"
+
+/*
+	从配置的section(toml解析出来的map)里按类型取值，没有或类型不对时返回默认值
+*/
+
+func String(section map[string]any, key string, def string) string {
+	if v, ok := section[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+func Int(section map[string]any, key string, def int64) int64 {
+	switch v := section[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return def
+}
+
+func Bool(section map[string]any, key string, def bool) bool {
+	if v, ok := section[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+//toml数组解析出来是[]interface{}
+func StringSlice(section map[string]any, key string, def []string) []string {
+	switch v := section[key].(type) {
+	case []string:
+		return v
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return def
+}
+
+//支持"10s"这样的字符串，数字按秒算
+func Duration(section map[string]any, key string, def time.Duration) time.Duration {
+	switch v := section[key].(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int64:
+		return time.Duration(v) * time.Second
+	}
+	return def
+}
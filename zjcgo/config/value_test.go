@@ -0,0 +1,48 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+	"testing"
+	"time"
+)
+
+func TestValueGetters(t *testing.T) {
+	var section map[string]any
+	_, err := toml.Decode(`
+name = "zjc"
+cap = 100
+ratio = 1.5
+debug = true
+hosts = ["a", "b"]
+timeout = "1m30s"
+interval = 10
+`, &section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if String(section, "name", "") != "zjc" || String(section, "cap", "def") != "def" {
+		t.Fatal("String")
+	}
+	if Int(section, "cap", 0) != 100 || Int(section, "ratio", 0) != 1 || Int(section, "name", 7) != 7 {
+		t.Fatal("Int")
+	}
+	if !Bool(section, "debug", false) || !Bool(section, "missing", true) || Bool(section, "name", false) {
+		t.Fatal("Bool")
+	}
+	if hosts := StringSlice(section, "hosts", nil); len(hosts) != 2 || hosts[1] != "b" {
+		t.Fatalf("StringSlice %v", hosts)
+	}
+	if def := StringSlice(section, "name", []string{"x"}); len(def) != 1 || def[0] != "x" {
+		t.Fatalf("StringSlice default %v", def)
+	}
+	if Duration(section, "timeout", 0) != 90*time.Second || Duration(section, "interval", 0) != 10*time.Second {
+		t.Fatal("Duration")
+	}
+	if Duration(section, "name", time.Second) != time.Second {
+		t.Fatal("Duration default")
+	}
+	//section不存在时是nil map
+	if Int(nil, "cap", 3) != 3 {
+		t.Fatal("nil section")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/zhengjingcheng/zjcgo/binding"
 	zjcLog "github.com/zhengjingcheng/zjcgo/log"
 	"github.com/zhengjingcheng/zjcgo/render"
+	"github.com/zhengjingcheng/zjcgo/sessions"
 	"io"
 	"log"
 	"mime/multipart"
@@ -16,6 +17,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 /*
@@ -28,10 +30,8 @@ import (
 
 //http.Request 服务器请求
 
-const (
-	defaultMaxMemory       = 32 << 20
-	defaultMultipartMemory = 32 << 20
-)
+//解析multipart表单时放在内存里的最大字节数，超过的部分写临时文件
+const defaultMultipartMemory = 32 << 20
 
 type Context struct {
 	W                     http.ResponseWriter
@@ -46,12 +46,51 @@ type Context struct {
 	Keys                  map[string]any
 	mu                    sync.RWMutex
 	sameSite              http.SameSite
+	writermem             responseWriter //W默认指向它
+	fullPath              string         //匹配上的路由模板，比如 /user/get/:id
+	sessionStore          sessions.Store
+	sessionOptions        *sessions.Options
+	session               *sessions.Session
+	templateData          map[string]any //只对当前请求生效的模板数据
+	maxBodySize           int64          //请求体最大字节数，<=0不限制
+	multipartMemory       int64
+	errs                  []error //Error收集的错误，处理完还没写响应的话渲染最后一个
+}
+
+//Context用完放回Engine.pool，下一个请求拿出来之前把上一个请求的东西清掉
+func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
+	c.writermem.reset(w)
+	c.W = &c.writermem
+	c.R = r
+	c.queryCache = nil
+	c.fromCache = nil
+	c.DisallowUnknownFields = false
+	c.IsValidate = false
+	c.StatusCode = 0
+	c.Logger = c.engine.Logger
+	c.mu.Lock()
+	c.Keys = nil
+	c.mu.Unlock()
+	c.sameSite = 0
+	c.fullPath = ""
+	c.sessionStore = nil
+	c.sessionOptions = nil
+	c.session = nil
+	c.templateData = nil
+	c.maxBodySize = c.engine.MaxBodySize
+	c.multipartMemory = c.engine.MaxMultipartMemory
+	c.errs = nil
 }
 
 func (c *Context) SetSameSite(s http.SameSite) {
 	c.sameSite = s
 }
 
+//匹配上的路由模板（不是真实路径），没匹配上返回空字符串
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
 /*
 ·············································参数提取模块（提取正常参数）·····················································
 */
@@ -59,6 +98,9 @@ func (c *Context) SetSameSite(s http.SameSite) {
 //http://xxx.com/user/add?id=1&age=20&username=张三
 //初始化
 func (c *Context) initQueryCache() {
+	if c.queryCache != nil {
+		return
+	}
 	if c.R != nil {
 		c.queryCache = c.R.URL.Query()
 	} else {
@@ -113,6 +155,56 @@ func (c *Context) Get(key string) (value any, exists bool) {
 	return
 }
 
+//key不存在直接panic
+func (c *Context) MustGet(key string) any {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("key \"" + key + "\" does not exist")
+}
+
+//取出来并转成T，不存在或者类型不对返回零值和false
+//claims, ok := zjcgo.GetAs[jwt.MapClaims](ctx, "claims")
+func GetAs[T any](c *Context, key string) (value T, ok bool) {
+	v, exists := c.Get(key)
+	if !exists {
+		return
+	}
+	value, ok = v.(T)
+	return
+}
+
+//以下不存在或者类型不对都返回零值
+func (c *Context) GetString(key string) string {
+	v, _ := GetAs[string](c, key)
+	return v
+}
+
+func (c *Context) GetInt(key string) int {
+	v, _ := GetAs[int](c, key)
+	return v
+}
+
+func (c *Context) GetInt64(key string) int64 {
+	v, _ := GetAs[int64](c, key)
+	return v
+}
+
+func (c *Context) GetBool(key string) bool {
+	v, _ := GetAs[bool](c, key)
+	return v
+}
+
+func (c *Context) GetTime(key string) time.Time {
+	v, _ := GetAs[time.Time](c, key)
+	return v
+}
+
+func (c *Context) GetStringSlice(key string) []string {
+	v, _ := GetAs[[]string](c, key)
+	return v
+}
+
 /*
 ·············································参数提取模块（提取map参数）·····················································
 */
@@ -152,7 +244,7 @@ func (c *Context) initFormCache() {
 		c.fromCache = make(url.Values) //如果没有表单参数缓存就创建出来
 		req := c.R
 		//如果有错误的话打印出来
-		if err := req.ParseMultipartForm(defaultMaxMemory); err != nil {
+		if err := req.ParseMultipartForm(c.multipartMemory); err != nil {
 			if !errors.Is(err, http.ErrNotMultipart) {
 				log.Println(err)
 			}
@@ -196,7 +288,7 @@ func (c *Context) PostFormMap(key string) (dicts map[string]string) {
 //提供单个文件
 func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
 	req := c.R
-	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+	if err := req.ParseMultipartForm(c.multipartMemory); err != nil {
 		return nil, err
 	}
 	file, header, err := req.FormFile(name)
@@ -212,7 +304,7 @@ func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
 
 //提供多个文件
 func (c *Context) MultipartForm() (*multipart.Form, error) {
-	err := c.R.ParseMultipartForm(defaultMultipartMemory)
+	err := c.R.ParseMultipartForm(c.multipartMemory)
 	return c.R.MultipartForm, err
 }
 
@@ -381,10 +473,51 @@ func (c *Context) HTML(status int, html string) error {
 }
 
 //实现提前加入模板的页面渲染函数
+//SetTemplateData设置的值会合并到map类型的data里，模板里用{{.csrfToken}}这样取
+//注册了ContextFunc的话用池子里绑定了当前Context的模板副本
 func (c *Context) Template(name string, data any) error {
+	if len(c.templateData) > 0 {
+		data = c.mergeTemplateData(data)
+	}
+	if ct := c.engine.templates; ct != nil {
+		entry, err := ct.get(c)
+		if err != nil {
+			return err
+		}
+		defer ct.put(entry)
+		return c.Render(http.StatusOK, &render.HTML{Data: data, IsTemplate: true, Template: entry.t, Name: name})
+	}
 	return c.Render(http.StatusOK, &render.HTML{Data: data, IsTemplate: true, Template: c.engine.HTMLRender.Template, Name: name})
 }
 
+//设置只在当前请求生效的模板数据，比如csrf的token、CSP的nonce
+func (c *Context) SetTemplateData(key string, value any) {
+	if c.templateData == nil {
+		c.templateData = make(map[string]any)
+	}
+	c.templateData[key] = value
+}
+
+//data是nil或者map时复制一份再合并，同名的以handler传的为准；其他类型原样返回
+func (c *Context) mergeTemplateData(data any) any {
+	var values map[string]any
+	switch d := data.(type) {
+	case nil:
+	case map[string]any:
+		values = d
+	default:
+		return data
+	}
+	merged := make(map[string]any, len(c.templateData)+len(values))
+	for k, v := range c.templateData {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
+
 //支持渲染jason格式
 func (c *Context) JSON(status int, data any) error {
 	return c.Render(status, &render.JSON{
@@ -424,7 +557,10 @@ func (c *Context) FileFromFS(filepath string, fs http.FileSystem) {
 
 //支持重定向
 func (c *Context) Redirect(status int, url string) error {
-	return c.Render(status, &render.Redirect{Code: status, Request: c.R, Location: url})
+	//http.Redirect自己写Location和状态码，不能先WriteHeader
+	c.StatusCode = status
+	r := &render.Redirect{Code: status, Request: c.R, Location: url}
+	return r.Render(c.W)
 }
 
 //支持string
@@ -433,6 +569,8 @@ func (c *Context) String(status int, format string, values ...any) error {
 }
 
 func (c *Context) Render(statusCode int, r render.Render) error {
+	//WriteHeader以后再改响应头就不生效了，Content-Type要先写
+	r.WriteContentType(c.W)
 	c.W.WriteHeader(statusCode)
 	err := r.Render(c.W)
 	c.StatusCode = statusCode
@@ -462,15 +600,15 @@ func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error
 func (c *Context) Fail(code int, msg string) {
 	c.String(code, msg)
 }
+
+//马上按错误处理函数写响应，没注册的话返回application/problem+json
 func (c *Context) ErrorHandle(err error) {
-	code, data := c.engine.errorHandler(err)
-	c.JSON(code, data)
+	c.renderError(err)
 }
 
 func (c *Context) HandlerWithError(code int, obj any, err error) {
 	if err != nil {
-		statusCode, data := c.engine.errorHandler(err)
-		c.JSON(statusCode, data)
+		c.renderError(err)
 		return
 	}
 	c.JSON(code, obj)
@@ -492,7 +630,17 @@ func (c *Context) SetCookie(name, value string, maxAge int, path, domain string,
 	})
 }
 
+//取cookie的值，没有时返回空字符串
 func (c *Context) GetCookie(name string) string {
-	cookie, _ := c.R.Cookie(name)
-	return cookie.String()
+	value, _ := c.Cookie(name)
+	return value
+}
+
+//取cookie的值(已经url解码)，没有时返回http.ErrNoCookie
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.R.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
 }
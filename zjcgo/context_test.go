@@ -0,0 +1,68 @@
+package zjcgo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//放回池子里的Context再拿出来，不能带着上一个请求的东西
+func TestContextReset(t *testing.T) {
+	engine := New()
+	ctx := engine.allocateContext().(*Context)
+	r := httptest.NewRequest(http.MethodPost, "/user?id=1", strings.NewReader(url.Values{"name": {"zjc"}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.reset(httptest.NewRecorder(), r)
+	ctx.Set("user", "zjc")
+	ctx.GetQuery("id")
+	ctx.GetPostForm("name")
+	ctx.Error(errors.New("boom"))
+	ctx.SetTemplateData("csrfToken", "token")
+	ctx.StatusCode = http.StatusTeapot
+
+	ctx.reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user?id=2", nil))
+	if _, ok := ctx.Get("user"); ok || len(ctx.Keys) != 0 {
+		t.Fatalf("keys not cleared: %v", ctx.Keys)
+	}
+	if ctx.GetQuery("id") != "2" {
+		t.Fatalf("query cache not cleared: %s", ctx.GetQuery("id"))
+	}
+	if v, ok := ctx.GetPostForm("name"); ok {
+		t.Fatalf("form cache not cleared: %s", v)
+	}
+	if len(ctx.Errors()) != 0 || len(ctx.templateData) != 0 || ctx.StatusCode != 0 {
+		t.Fatalf("errors %v template data %v status %d", ctx.Errors(), ctx.templateData, ctx.StatusCode)
+	}
+	if ctx.written() {
+		t.Fatal("new response already written")
+	}
+}
+
+func TestGetAs(t *testing.T) {
+	ctx := &Context{}
+	ctx.Set("count", int64(3))
+	ctx.Set("name", "zjc")
+	ctx.Set("tags", []string{"a"})
+	if v, ok := GetAs[int64](ctx, "count"); !ok || v != 3 {
+		t.Fatalf("GetAs[int64] = %d, %v", v, ok)
+	}
+	//类型不对返回零值和false，不panic
+	if v, ok := GetAs[int](ctx, "count"); ok || v != 0 {
+		t.Fatalf("GetAs[int] on int64 = %d, %v", v, ok)
+	}
+	if v, ok := GetAs[string](ctx, "missing"); ok || v != "" {
+		t.Fatalf("missing key = %q, %v", v, ok)
+	}
+	if ctx.GetInt("count") != 0 || ctx.GetInt("name") != 0 || ctx.GetInt64("count") != 3 {
+		t.Fatal("GetInt type mismatch should return zero")
+	}
+	if ctx.GetString("count") != "" || ctx.GetString("name") != "zjc" || ctx.GetBool("name") {
+		t.Fatal("GetString/GetBool")
+	}
+	if s := ctx.GetStringSlice("tags"); len(s) != 1 || s[0] != "a" {
+		t.Fatalf("GetStringSlice %v", s)
+	}
+}
@@ -0,0 +1,105 @@
+package zjcgo
+
+import (
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/securecookie"
+	"time"
+)
+
+/*
+	签名和加密的cookie
+	engine.SetCookieSigningKeys([]byte("new key"), []byte("old key"))
+	engine.SetCookieMaxAge(24 * time.Hour) //可选，签发超过一天的cookie不再认
+	ctx.SetSignedCookie("user", "zjc", 3600, "/", "", false, true)
+	user, err := ctx.GetSignedCookie("user")
+*/
+
+var (
+	ErrNoSigningKeys    = errors.New("zjcgo: cookie signing keys not set")
+	ErrNoEncryptionKeys = errors.New("zjcgo: cookie encryption keys not set")
+)
+
+//设置cookie签名的key，第一个用来签名，其余的只用来验证旧cookie
+func (e *Engine) SetCookieSigningKeys(keys ...[]byte) error {
+	signer, err := securecookie.NewSigner(keys...)
+	if err != nil {
+		return err
+	}
+	signer.MaxAge = e.cookieMaxAge
+	e.cookieSigner = signer
+	return nil
+}
+
+//设置cookie加密的key(16、24或32字节)，第一个用来加密，其余的只用来解密旧cookie
+func (e *Engine) SetCookieEncryptionKeys(keys ...[]byte) error {
+	encrypter, err := securecookie.NewEncrypter(keys...)
+	if err != nil {
+		return err
+	}
+	encrypter.MaxAge = e.cookieMaxAge
+	e.cookieEncrypter = encrypter
+	return nil
+}
+
+//签名和加密的cookie从签发开始最多认多久，<=0不限制，设置key前后调用都可以，要在启动服务前设置
+func (e *Engine) SetCookieMaxAge(maxAge time.Duration) {
+	e.cookieMaxAge = maxAge
+	if e.cookieSigner != nil {
+		e.cookieSigner.MaxAge = maxAge
+	}
+	if e.cookieEncrypter != nil {
+		e.cookieEncrypter.MaxAge = maxAge
+	}
+}
+
+func (c *Context) SetSignedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	if c.engine.cookieSigner == nil {
+		return ErrNoSigningKeys
+	}
+	c.SetCookie(name, c.engine.cookieSigner.Sign(name, []byte(value)), maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+//签名不对返回securecookie.ErrInvalid
+func (c *Context) GetSignedCookie(name string) (string, error) {
+	if c.engine.cookieSigner == nil {
+		return "", ErrNoSigningKeys
+	}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := c.engine.cookieSigner.Verify(name, value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (c *Context) SetEncryptedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	if c.engine.cookieEncrypter == nil {
+		return ErrNoEncryptionKeys
+	}
+	encrypted, err := c.engine.cookieEncrypter.Encrypt(name, []byte(value))
+	if err != nil {
+		return err
+	}
+	c.SetCookie(name, encrypted, maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+//解密失败返回securecookie.ErrInvalid
+func (c *Context) GetEncryptedCookie(name string) (string, error) {
+	if c.engine.cookieEncrypter == nil {
+		return "", ErrNoEncryptionKeys
+	}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := c.engine.cookieEncrypter.Decrypt(name, value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
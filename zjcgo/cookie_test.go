@@ -0,0 +1,47 @@
+package zjcgo
+
+import (
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/securecookie"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//SetCookieMaxAge在设置key前后调用都要生效
+func TestCookieMaxAge(t *testing.T) {
+	engine := New()
+	engine.SetCookieMaxAge(time.Hour)
+	if err := engine.SetCookieSigningKeys([]byte("sign key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.SetCookieEncryptionKeys([]byte("0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	if engine.cookieSigner.MaxAge != time.Hour || engine.cookieEncrypter.MaxAge != time.Hour {
+		t.Fatalf("max age not applied to new keys: %v %v", engine.cookieSigner.MaxAge, engine.cookieEncrypter.MaxAge)
+	}
+
+	engine.SetCookieMaxAge(time.Nanosecond)
+	w := httptest.NewRecorder()
+	ctx := &Context{W: w, engine: engine}
+	if err := ctx.SetSignedCookie("user", "zjc", 0, "/", "", false, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.SetEncryptedCookie("uid", "1", 0, "/", "", false, true); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	ctx.R = r
+	time.Sleep(time.Millisecond)
+	if _, err := ctx.GetSignedCookie("user"); !errors.Is(err, securecookie.ErrExpired) {
+		t.Fatalf("signed cookie: want ErrExpired, got %v", err)
+	}
+	if _, err := ctx.GetEncryptedCookie("uid"); !errors.Is(err, securecookie.ErrExpired) {
+		t.Fatalf("encrypted cookie: want ErrExpired, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/securecookie"
+	"html/template"
+	"mime"
+	"net/http"
+	"time"
+)
+
+/*
+	CSRF防护中间件
+	双重提交cookie：token用Keys做HMAC签名后放在cookie里，提交时header或表单里的token要和cookie里验签后的一致
+	设置了Bind时签名里带上会话或用户标识，别人种进来的cookie换了身份就验不过
+	同步令牌：token放在会话里，zjcgo.Sessions中间件要在它外层(后注册的中间件先执行)
+
+	engine.SetFuncMap(csrf.FuncMap())
+	engine.LoadTemplate("tpl/*.html")
+	g.Post("/add", handler, csrf.New(csrf.Config{Keys: [][]byte{[]byte("csrf key")}}))
+	模板里：<input type="hidden" name="csrf_token" value="{{csrfToken}}">
+	模板数据是nil或map时也可以用{{.csrfToken}}
+*/
+
+const (
+	ModeDoubleSubmit = iota //双重提交cookie
+	ModeSynchronizer        //同步令牌，token存在会话里
+)
+
+const (
+	DefaultCookieName = "zjcgo_csrf"
+	DefaultHeaderName = "X-CSRF-Token"
+	DefaultFormField  = "csrf_token"
+	DefaultSessionKey = "csrf_token"
+	TemplateFuncName  = "csrfToken"
+	TemplateKey       = "csrfToken" //Context.Template合并进模板数据的key
+	contextKey        = "zjcgo/csrf"
+	tokenLength       = 32
+)
+
+var (
+	ErrTokenMissing = errors.New("csrf: token missing")
+	ErrTokenInvalid = errors.New("csrf: token invalid")
+	ErrNoKeys       = errors.New("csrf: keys required in double submit mode")
+)
+
+type Config struct {
+	Mode       int
+	HeaderName string
+	FormField  string
+	//双重提交cookie模式
+	//签名cookie的key，第一个用来签名，其余的只用来验证旧cookie
+	Keys [][]byte
+	//token绑定的会话ID或用户ID，可选
+	Bind           func(ctx *zjcgo.Context) string
+	CookieName     string
+	CookiePath     string
+	CookieDomain   string
+	CookieMaxAge   int //默认12小时
+	CookieSecure   bool
+	CookieHTTPOnly bool //前端要从cookie里读token放到header时不能开
+	//同步令牌模式
+	SessionKey string
+	//校验失败的处理，默认403
+	ErrorHandler func(ctx *zjcgo.Context, err error)
+
+	signer *securecookie.Signer
+}
+
+func New(conf Config) zjcgo.MiddlewareFunc {
+	if conf.HeaderName == "" {
+		conf.HeaderName = DefaultHeaderName
+	}
+	if conf.FormField == "" {
+		conf.FormField = DefaultFormField
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = DefaultCookieName
+	}
+	if conf.CookieMaxAge == 0 {
+		conf.CookieMaxAge = 12 * 3600
+	}
+	if conf.SessionKey == "" {
+		conf.SessionKey = DefaultSessionKey
+	}
+	if conf.ErrorHandler == nil {
+		conf.ErrorHandler = defaultErrorHandler
+	}
+	if conf.Mode == ModeDoubleSubmit {
+		signer, err := securecookie.NewSigner(conf.Keys...)
+		if err != nil {
+			panic(ErrNoKeys)
+		}
+		if conf.CookieMaxAge > 0 {
+			signer.MaxAge = time.Duration(conf.CookieMaxAge) * time.Second
+		}
+		conf.signer = signer
+	}
+	return func(next zjcgo.HandlerFunc) zjcgo.HandlerFunc {
+		return func(ctx *zjcgo.Context) {
+			token, err := conf.load(ctx)
+			if err != nil {
+				conf.ErrorHandler(ctx, err)
+				return
+			}
+			if !safeMethod(ctx.R.Method) {
+				if err := conf.verify(ctx, token); err != nil {
+					conf.ErrorHandler(ctx, err)
+					return
+				}
+			}
+			ctx.Set(contextKey, token)
+			ctx.SetTemplateData(TemplateKey, token)
+			next(ctx)
+		}
+	}
+}
+
+//当前请求的token，没有经过中间件时返回空字符串
+func Token(ctx *zjcgo.Context) string {
+	if v, ok := ctx.Get(contextKey); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+//模板函数csrfToken，从当前请求的Context里取token，要在LoadTemplate之前注册
+func FuncMap() template.FuncMap {
+	return template.FuncMap{TemplateFuncName: zjcgo.ContextFunc(func(ctx *zjcgo.Context) any {
+		return Token(ctx)
+	})}
+}
+
+//取出已有的token，没有就生成一个新的
+func (conf *Config) load(ctx *zjcgo.Context) (string, error) {
+	if conf.Mode == ModeSynchronizer {
+		session := ctx.Session()
+		if token, ok := session.Get(conf.SessionKey).(string); ok && token != "" {
+			return token, nil
+		}
+		token := newToken()
+		session.Set(conf.SessionKey, token)
+		if err := session.Save(); err != nil {
+			return "", err
+		}
+		return token, nil
+	}
+	name := conf.signName(ctx)
+	if value, err := ctx.Cookie(conf.CookieName); err == nil {
+		if raw, err := conf.signer.Verify(name, value); err == nil && validToken(string(raw)) {
+			return string(raw), nil
+		}
+	}
+	token := newToken()
+	ctx.SetCookie(conf.CookieName, conf.signer.Sign(name, []byte(token)), conf.CookieMaxAge, conf.CookiePath, conf.CookieDomain, conf.CookieSecure, conf.CookieHTTPOnly)
+	return token, nil
+}
+
+//签名时cookie名字和绑定的身份一起参与HMAC
+func (conf *Config) signName(ctx *zjcgo.Context) string {
+	if conf.Bind == nil {
+		return conf.CookieName
+	}
+	return conf.CookieName + "|" + conf.Bind(ctx)
+}
+
+//header优先，其次表单字段
+func (conf *Config) verify(ctx *zjcgo.Context, token string) error {
+	submitted := ctx.R.Header.Get(conf.HeaderName)
+	if submitted == "" && isForm(ctx.R) {
+		submitted = ctx.R.PostFormValue(conf.FormField)
+	}
+	if submitted == "" {
+		return ErrTokenMissing
+	}
+	if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+func defaultErrorHandler(ctx *zjcgo.Context, err error) {
+	ctx.String(http.StatusForbidden, "%s", err.Error())
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func isForm(r *http.Request) bool {
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return ct == "application/x-www-form-urlencoded" || ct == "multipart/form-data"
+}
+
+func newToken() string {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func validToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == tokenLength
+}
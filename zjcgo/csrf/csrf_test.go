@@ -0,0 +1,175 @@
+package csrf
+
+import (
+	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/sessions"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEngine(conf Config) *zjcgo.Engine {
+	engine := zjcgo.New()
+	engine.SetHtmlTemplate(template.Must(template.New("form").Parse(`{{.csrfToken}}`)))
+	g := engine.Group("user")
+	g.Use(New(conf))
+	g.Get("/form", func(ctx *zjcgo.Context) {
+		_ = ctx.Template("form", nil)
+	})
+	g.Post("/add", func(ctx *zjcgo.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	return engine
+}
+
+func do(engine *zjcgo.Engine, method, path, token string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		r.Header.Set(DefaultHeaderName, token)
+	}
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestDoubleSubmit(t *testing.T) {
+	keys := [][]byte{[]byte("csrf key")}
+	engine := newEngine(Config{Keys: keys})
+	w := do(engine, http.MethodGet, "/user/form", "", nil)
+	token := w.Body.String()
+	cookies := w.Result().Cookies()
+	if !validToken(token) || len(cookies) != 1 {
+		t.Fatalf("token %q cookies %v", token, cookies)
+	}
+	if strings.Contains(cookies[0].Value, token) {
+		t.Fatal("cookie should carry the signed token")
+	}
+	if w := do(engine, http.MethodPost, "/user/add", token, cookies); w.Code != http.StatusOK {
+		t.Fatalf("valid token: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(engine, http.MethodPost, "/user/add", "", cookies); w.Code != http.StatusForbidden {
+		t.Fatalf("missing token: %d", w.Code)
+	}
+	//攻击者种进来的未签名cookie
+	planted := newToken()
+	w = do(engine, http.MethodPost, "/user/add", planted, []*http.Cookie{{Name: DefaultCookieName, Value: planted}})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unsigned cookie: %d", w.Code)
+	}
+	//别的key签出来的cookie
+	other := newEngine(Config{Keys: [][]byte{[]byte("other key")}})
+	w = do(other, http.MethodGet, "/user/form", "", nil)
+	w = do(engine, http.MethodPost, "/user/add", w.Body.String(), w.Result().Cookies())
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("foreign signature: %d", w.Code)
+	}
+}
+
+func TestDoubleSubmitBind(t *testing.T) {
+	engine := newEngine(Config{
+		Keys: [][]byte{[]byte("csrf key")},
+		Bind: func(ctx *zjcgo.Context) string { return ctx.R.Header.Get("X-User") },
+	})
+	r := httptest.NewRequest(http.MethodGet, "/user/form", nil)
+	r.Header.Set("X-User", "attacker")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	token, cookies := w.Body.String(), w.Result().Cookies()
+
+	//攻击者自己拿到的合法cookie种给别的用户
+	r = httptest.NewRequest(http.MethodPost, "/user/add", nil)
+	r.Header.Set("X-User", "victim")
+	r.Header.Set(DefaultHeaderName, token)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("cookie bound to another user: %d", w.Code)
+	}
+}
+
+func TestDoubleSubmitRequiresKeys(t *testing.T) {
+	defer func() {
+		if recover() != ErrNoKeys {
+			t.Fatal("want ErrNoKeys panic")
+		}
+	}()
+	New(Config{})
+}
+
+func TestSynchronizer(t *testing.T) {
+	store := sessions.NewMemoryStore(time.Minute)
+	defer store.Close()
+	engine := zjcgo.New()
+	engine.SetHtmlTemplate(template.Must(template.New("form").Parse(`{{.csrfToken}}`)))
+	g := engine.Group("user")
+	g.Use(New(Config{Mode: ModeSynchronizer}))
+	//Sessions要在csrf外层，后注册的先执行
+	g.Use(zjcgo.Sessions(store, nil))
+	g.Get("/form", func(ctx *zjcgo.Context) {
+		_ = ctx.Template("form", nil)
+	})
+	g.Post("/add", func(ctx *zjcgo.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	w := do(engine, http.MethodGet, "/user/form", "", nil)
+	token, cookies := w.Body.String(), w.Result().Cookies()
+	if !validToken(token) || len(cookies) != 1 || cookies[0].Name == DefaultCookieName {
+		t.Fatalf("token %q cookies %v", token, cookies)
+	}
+	if w := do(engine, http.MethodPost, "/user/add", token, cookies); w.Code != http.StatusOK {
+		t.Fatalf("valid token: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(engine, http.MethodPost, "/user/add", newToken(), cookies); w.Code != http.StatusForbidden {
+		t.Fatalf("wrong token: %d", w.Code)
+	}
+	//换一个会话，token对不上
+	if w := do(engine, http.MethodPost, "/user/add", token, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("token without session: %d", w.Code)
+	}
+}
+
+//模板数据是结构体时用模板函数取token
+func TestTemplateFuncStructData(t *testing.T) {
+	dir := t.TempDir()
+	tpl := `{{define "form"}}{{.Name}}:{{csrfToken}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "form.html"), []byte(tpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := zjcgo.New()
+	engine.SetFuncMap(FuncMap())
+	engine.LoadTemplate(filepath.Join(dir, "*.html"))
+	g := engine.Group("user")
+	g.Use(New(Config{Keys: [][]byte{[]byte("csrf key")}}))
+	g.Get("/form", func(ctx *zjcgo.Context) {
+		_ = ctx.Template("form", &struct{ Name string }{"zjc"})
+	})
+	g.Post("/add", func(ctx *zjcgo.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		w := do(engine, http.MethodGet, "/user/form", "", nil)
+		token := strings.TrimPrefix(w.Body.String(), "zjc:")
+		if !validToken(token) {
+			t.Fatalf("rendered %q", w.Body.String())
+		}
+		if w := do(engine, http.MethodPost, "/user/add", token, w.Result().Cookies()); w.Code != http.StatusOK {
+			t.Fatalf("token from template rejected: %d", w.Code)
+		}
+		tokens = append(tokens, token)
+	}
+	//复用的模板副本要绑定到新的请求
+	if tokens[0] == tokens[1] {
+		t.Fatal("template returned the previous request's token")
+	}
+}
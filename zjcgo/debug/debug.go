@@ -0,0 +1,120 @@
+package debug
+
+import (
+	"github.com/zhengjingcheng/zjcgo"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+)
+
+/*
+	调试接口，pprof和运行时信息，一般挂在带认证的路由组下面
+	单独一个包，net/http/pprof的init会往http.DefaultServeMux上注册/debug/pprof/，只有用到的程序才引入
+	g := engine.Group("debug")
+	g.Use(accounts.BasicAuth)
+	debug.Register(g)
+*/
+
+var startTime = time.Now()
+
+//engine.Group返回的路由组
+type Router interface {
+	Mount(prefix string, handler http.Handler, middlewareFunc ...zjcgo.MiddlewareFunc)
+	Get(name string, handle zjcgo.HandlerFunc, middlewareFunc ...zjcgo.MiddlewareFunc)
+}
+
+//挂载 /pprof/ 和 /runtime
+func Register(r Router, middlewareFunc ...zjcgo.MiddlewareFunc) {
+	r.Mount("/pprof", PprofHandler(), middlewareFunc...)
+	r.Get("/runtime", RuntimeStats, middlewareFunc...)
+}
+
+//net/http/pprof的handler，挂载的路径不限于/debug/pprof/
+func PprofHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		switch name {
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			//pprof.Index按/debug/pprof/前缀取profile名字
+			req := new(http.Request)
+			*req = *r
+			u := *r.URL
+			u.Path = "/debug/pprof/" + name
+			req.URL = &u
+			pprof.Index(w, req)
+		}
+	})
+}
+
+type MemStats struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	StackInuse   uint64 `json:"stack_inuse"`
+	Mallocs      uint64 `json:"mallocs"`
+	Frees        uint64 `json:"frees"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+	LastGC       string `json:"last_gc,omitempty"`
+	NextGC       uint64 `json:"next_gc"`
+}
+
+type RuntimeInfo struct {
+	GoVersion  string   `json:"go_version"`
+	GOOS       string   `json:"goos"`
+	GOARCH     string   `json:"goarch"`
+	NumCPU     int      `json:"num_cpu"`
+	GOMAXPROCS int      `json:"gomaxprocs"`
+	Goroutines int      `json:"goroutines"`
+	CgoCalls   int64    `json:"cgo_calls"`
+	Uptime     string   `json:"uptime"`
+	Memory     MemStats `json:"memory"`
+}
+
+//协程数，GC，内存信息
+func RuntimeStats(ctx *zjcgo.Context) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	info := RuntimeInfo{
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		CgoCalls:   runtime.NumCgoCall(),
+		Uptime:     time.Since(startTime).String(),
+		Memory: MemStats{
+			Alloc:        m.Alloc,
+			TotalAlloc:   m.TotalAlloc,
+			Sys:          m.Sys,
+			HeapAlloc:    m.HeapAlloc,
+			HeapInuse:    m.HeapInuse,
+			HeapObjects:  m.HeapObjects,
+			StackInuse:   m.StackInuse,
+			Mallocs:      m.Mallocs,
+			Frees:        m.Frees,
+			NumGC:        m.NumGC,
+			PauseTotalNs: m.PauseTotalNs,
+			NextGC:       m.NextGC,
+		},
+	}
+	if m.LastGC > 0 {
+		info.Memory.LastGC = time.Unix(0, int64(m.LastGC)).Format(time.RFC3339)
+	}
+	ctx.W.Header().Set("Cache-Control", "no-store")
+	_ = ctx.JSON(http.StatusOK, info)
+}
@@ -0,0 +1,33 @@
+package debug
+
+import (
+	"encoding/json"
+	"github.com/zhengjingcheng/zjcgo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	engine := zjcgo.New()
+	g := engine.Group("debug")
+	Register(g)
+	for path, want := range map[string]string{
+		"/debug/pprof/":                  "goroutine",
+		"/debug/pprof/goroutine?debug=1": "goroutine profile",
+		"/debug/pprof/cmdline":           "",
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("%s: %d %.100q", path, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	var info RuntimeInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil || info.Goroutines == 0 || info.GoVersion == "" {
+		t.Fatalf("runtime stats %q: %v", w.Body.String(), err)
+	}
+}
@@ -0,0 +1,87 @@
+package zjcgo
+
+import (
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"github.com/zhengjingcheng/zjcgo/render"
+	"net/http"
+)
+
+/*
+	业务错误的响应
+	ctx.Error(ErrGoodsNotFound.WithDetail("id", id)) 先收集，处理函数返回后还没写响应就渲染最后一个
+	默认返回application/problem+json，想用统一的返回结构就注册一个ErrorHandler：
+	engine.RegisterErrorHandler(zjcgo.EnvelopeErrorHandler(func(e *mserror.Error) any {
+		return &model.Result{Code: e.Code, Msg: e.Message, Data: e.Details}
+	}))
+*/
+
+//收集错误，返回原来的err方便直接return
+func (c *Context) Error(err error) error {
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+	return err
+}
+
+//这次请求收集到的错误
+func (c *Context) Errors() []error {
+	return c.errs
+}
+
+//最后一个收集到的错误，没有返回nil
+func (c *Context) LastError() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs[len(c.errs)-1]
+}
+
+//把业务错误包成统一结构，http状态码用错误自己的
+func EnvelopeErrorHandler(envelope func(e *mserror.Error) any) ErrorHandler {
+	return func(err error) (int, any) {
+		e := mserror.From(err)
+		return e.Status, envelope(e)
+	}
+}
+
+func (c *Context) renderError(err error) {
+	e := mserror.From(err)
+	//500以上的把原因打出来，客户端只看到注册的信息
+	if e.Status >= http.StatusInternalServerError && c.Logger != nil {
+		c.Logger.With("code", e.Code).Error(err.Error())
+	}
+	if c.engine.errorHandler != nil {
+		code, data := c.engine.errorHandler(err)
+		c.JSON(code, data)
+		return
+	}
+	instance := ""
+	if c.R != nil {
+		instance = c.R.URL.Path
+	}
+	c.Render(e.Status, &render.ProblemJSON{Data: e.Problem(instance)})
+}
+
+//处理完了还没写响应，把收集到的错误写出去
+func (c *Context) flushErrors() {
+	if len(c.errs) == 0 || c.written() {
+		return
+	}
+	c.renderError(c.LastError())
+}
+
+//ctx.W可能被Conditional、ResponseCache换成了缓冲的writer，要看当前这个写没写过
+func (c *Context) written() bool {
+	if w, ok := c.W.(ResponseWriter); ok {
+		return w.Written()
+	}
+	return c.writermem.Written()
+}
+
+//紧挨着处理函数，外层的中间件能看到错误响应的状态码
+func renderErrors(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		next(ctx)
+		ctx.flushErrors()
+	}
+}
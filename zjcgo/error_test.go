@@ -0,0 +1,67 @@
+package zjcgo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContextError(t *testing.T) {
+	engine := New()
+	g := engine.Group("api")
+	g.Get("/collect", func(ctx *Context) {
+		ctx.Error(mserror.ErrNotFound.WithMessage("no goods").WithDetail("id", 1))
+	})
+	g.Get("/handle", func(ctx *Context) {
+		ctx.ErrorHandle(errors.New("db down"))
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/collect", nil))
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+		t.Fatalf("status %d content-type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var problem mserror.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Code != 404 || problem.Detail != "no goods" || problem.Instance != "/api/collect" || problem.Details["id"] != float64(1) {
+		t.Fatalf("unexpected problem %+v", problem)
+	}
+
+	//没注册ErrorHandler也不能panic
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/handle", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "db down") {
+		t.Fatalf("status %d body %s", w.Code, w.Body.String())
+	}
+
+	engine.RegisterErrorHandler(EnvelopeErrorHandler(func(e *mserror.Error) any {
+		return map[string]any{"code": e.Code, "msg": e.Message}
+	}))
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/collect", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"code":404,"msg":"no goods"}` {
+		t.Fatalf("envelope: %d %s", w.Code, w.Body.String())
+	}
+}
+
+//Conditional把ctx.W换成了缓冲的writer，已经写了body的不能再追加错误响应
+func TestContextErrorBehindConditional(t *testing.T) {
+	engine := New()
+	g := engine.Group("api")
+	g.Use(Conditional)
+	g.Get("/partial", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, map[string]int{"id": 1})
+		ctx.Error(mserror.ErrInternal.Wrap(errors.New("audit failed")))
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/partial", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":1}` {
+		t.Fatalf("status %d body %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,174 @@
+package zjcgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	健康检查 /healthz /readyz，检查项并发执行，结果短时间缓存，关键检查项失败时返回503
+*/
+
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+//函数形式的检查项
+type CheckerFunc struct {
+	CheckName string
+	Fn        func(ctx context.Context) error
+}
+
+func (c CheckerFunc) Name() string {
+	return c.CheckName
+}
+
+func (c CheckerFunc) Check(ctx context.Context) error {
+	return c.Fn(ctx)
+}
+
+type nonCritical struct {
+	Checker
+}
+
+func (nonCritical) Critical() bool {
+	return false
+}
+
+//非关键检查项，失败时状态为degraded，仍然返回200
+func NonCritical(c Checker) Checker {
+	return nonCritical{c}
+}
+
+func isCritical(c Checker) bool {
+	if v, ok := c.(interface{ Critical() bool }); ok {
+		return v.Critical()
+	}
+	return true
+}
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFail     = "fail"
+)
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Time   time.Time              `json:"time"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Health struct {
+	Timeout  time.Duration //单个检查项的超时时间
+	CacheTTL time.Duration //结果缓存时间，避免探针太频繁打到数据库
+	checks   []Checker
+	mu       sync.Mutex
+	report   *HealthReport
+	expires  time.Time
+}
+
+//注册健康检查接口，返回的Health可以调整超时和缓存时间
+func (e *Engine) Health(path string, checks ...Checker) *Health {
+	h := &Health{
+		Timeout:  2 * time.Second,
+		CacheTTL: time.Second,
+		checks:   checks,
+	}
+	g := e.Group(strings.Trim(path, "/"))
+	g.Get("/", h.Handle)
+	return h
+}
+
+func (h *Health) Handle(ctx *Context) {
+	report := h.Report(ctx.R.Context())
+	code := http.StatusOK
+	if report.Status == HealthFail {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.W.Header().Set("Cache-Control", "no-store")
+	_ = ctx.JSON(code, report)
+}
+
+//执行所有检查项，缓存有效期内直接返回上次的结果
+func (h *Health) Report(ctx context.Context) *HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.report != nil && time.Now().Before(h.expires) {
+		return h.report
+	}
+	report := &HealthReport{Status: HealthOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	results := make([]CheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	for i, c := range h.checks {
+		r := results[i]
+		report.Checks[c.Name()] = r
+		if r.Status == HealthOK {
+			continue
+		}
+		if r.Critical {
+			report.Status = HealthFail
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+	report.Time = time.Now()
+	//调用方的请求取消了，失败是它自己造成的，不能缓存给别的探针
+	if ctx.Err() == nil {
+		h.report = report
+		h.expires = report.Time.Add(h.CacheTTL)
+	}
+	return report
+}
+
+func (h *Health) run(ctx context.Context, c Checker) CheckResult {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- errors.New("check panic")
+			}
+		}()
+		done <- c.Check(ctx)
+	}()
+	var err error
+	//检查项不理会ctx的时候也要按时返回
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	r := CheckResult{Status: HealthOK, Critical: isCritical(c), Duration: time.Since(start).String()}
+	if err != nil {
+		r.Status = HealthFail
+		r.Error = err.Error()
+	}
+	return r
+}
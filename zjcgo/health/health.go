@@ -0,0 +1,111 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zhengjingcheng/zjcgo/orm"
+	"github.com/zhengjingcheng/zjcgo/rpc"
+	"github.com/zhengjingcheng/zjcgo/zjcpool"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"strconv"
+)
+
+/*
+	内置的健康检查项，实现了zjcgo.Checker
+	engine.Health("/readyz", health.DB("mysql", db), health.Tcp("goods", rpc.DefaultOption))
+*/
+
+type DBChecker struct {
+	name string
+	db   *orm.ZjcDb
+}
+
+//数据库ping
+func DB(name string, db *orm.ZjcDb) *DBChecker {
+	return &DBChecker{name: name, db: db}
+}
+
+func (c *DBChecker) Name() string {
+	return c.name
+}
+
+func (c *DBChecker) Check(ctx context.Context) error {
+	return c.db.Ping(ctx)
+}
+
+type TcpChecker struct {
+	name   string
+	option rpc.TcpClientOption
+}
+
+//能否连上tcp rpc服务
+func Tcp(name string, option rpc.TcpClientOption) *TcpChecker {
+	return &TcpChecker{name: name, option: option}
+}
+
+func (c *TcpChecker) Name() string {
+	return c.name
+}
+
+func (c *TcpChecker) Check(ctx context.Context) error {
+	addr := net.JoinHostPort(c.option.Host, strconv.Itoa(c.option.Port))
+	d := net.Dialer{Timeout: c.option.ConnectionTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+type GrpcChecker struct {
+	name    string
+	client  *rpc.ZjcrpcClient
+	service string
+}
+
+//grpc标准健康检查，service为空表示整个服务端
+func Grpc(name string, client *rpc.ZjcrpcClient, service string) *GrpcChecker {
+	return &GrpcChecker{name: name, client: client, service: service}
+}
+
+func (c *GrpcChecker) Name() string {
+	return c.name
+}
+
+func (c *GrpcChecker) Check(ctx context.Context) error {
+	rsp, err := grpc_health_v1.NewHealthClient(c.client.Conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: c.service})
+	if err != nil {
+		return err
+	}
+	if rsp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpc status %s", rsp.Status)
+	}
+	return nil
+}
+
+type PoolChecker struct {
+	name string
+	pool *zjcpool.Pool
+}
+
+//协程池没有关闭且没有打满
+func Pool(name string, pool *zjcpool.Pool) *PoolChecker {
+	return &PoolChecker{name: name, pool: pool}
+}
+
+func (c *PoolChecker) Name() string {
+	return c.name
+}
+
+func (c *PoolChecker) Check(ctx context.Context) error {
+	if c.pool.IsClosed() {
+		return errors.New("pool closed")
+	}
+	running, capacity := c.pool.Running(), c.pool.Cap()
+	if running >= capacity && c.pool.Idle() == 0 {
+		return fmt.Errorf("pool saturated: %d/%d", running, capacity)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package zjcgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func getHealth(t *testing.T, engine *Engine) (int, HealthReport) {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("status %d body %q: %v", w.Code, w.Body.String(), err)
+	}
+	return w.Code, report
+}
+
+func TestHealthStatus(t *testing.T) {
+	ok := CheckerFunc{CheckName: "db", Fn: func(ctx context.Context) error { return nil }}
+	fail := CheckerFunc{CheckName: "redis", Fn: func(ctx context.Context) error { return errors.New("connection refused") }}
+
+	//非关键检查项失败还是200
+	engine := New()
+	engine.Health("/healthz", ok, NonCritical(fail))
+	code, report := getHealth(t, engine)
+	if code != http.StatusOK || report.Status != HealthDegraded || report.Checks["redis"].Critical {
+		t.Fatalf("non critical failure: %d %+v", code, report)
+	}
+
+	engine = New()
+	engine.Health("/healthz", ok, fail)
+	code, report = getHealth(t, engine)
+	if code != http.StatusServiceUnavailable || report.Status != HealthFail {
+		t.Fatalf("critical failure: %d %+v", code, report)
+	}
+	if r := report.Checks["redis"]; r.Status != HealthFail || r.Error != "connection refused" || report.Checks["db"].Status != HealthOK {
+		t.Fatalf("check results %+v", report.Checks)
+	}
+}
+
+func TestHealthTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	//不理会ctx的检查项也要按时返回
+	slow := CheckerFunc{CheckName: "slow", Fn: func(ctx context.Context) error {
+		<-block
+		return nil
+	}}
+	engine := New()
+	h := engine.Health("/healthz", slow)
+	h.Timeout = 20 * time.Millisecond
+	code, report := getHealth(t, engine)
+	if code != http.StatusServiceUnavailable || report.Checks["slow"].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("timeout: %d %+v", code, report)
+	}
+}
+
+func TestHealthCacheTTL(t *testing.T) {
+	var calls int32
+	counter := CheckerFunc{CheckName: "db", Fn: func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}}
+	engine := New()
+	h := engine.Health("/healthz", counter)
+	h.CacheTTL = 50 * time.Millisecond
+	getHealth(t, engine)
+	getHealth(t, engine)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("checks ran %d times within ttl", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	getHealth(t, engine)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("checks ran %d times after ttl", n)
+	}
+}
+
+//请求取消导致的失败不缓存
+func TestHealthCacheSkipsCanceled(t *testing.T) {
+	check := CheckerFunc{CheckName: "db", Fn: func(ctx context.Context) error {
+		return ctx.Err()
+	}}
+	engine := New()
+	h := engine.Health("/healthz", check)
+	h.CacheTTL = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := h.Report(ctx); report.Status != HealthFail {
+		t.Fatalf("canceled report %+v", report)
+	}
+	if code, report := getHealth(t, engine); code != http.StatusOK {
+		t.Fatalf("canceled result served from cache: %d %+v", code, report)
+	}
+}
@@ -0,0 +1,161 @@
+package httpcache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+	响应缓存的存储，默认是分片的内存LRU
+*/
+
+type Response struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Tags    []string
+	Expires time.Time
+}
+
+type Store interface {
+	Get(key string) (*Response, bool)
+	Set(key string, rsp *Response)
+	Delete(key string)
+	//删除带有这些标签的所有缓存
+	DeleteTags(tags ...string)
+}
+
+type entry struct {
+	key string
+	rsp *Response
+}
+
+type shard struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{} //tag -> keys
+}
+
+//分片减少锁竞争，每个分片各自做LRU淘汰
+type LRUStore struct {
+	shards []*shard
+}
+
+//capacity是总的条目数，shards<=0时默认16
+func NewLRUStore(capacity int, shards int) *LRUStore {
+	if shards <= 0 {
+		shards = 16
+	}
+	per := capacity / shards
+	if per <= 0 {
+		per = 1
+	}
+	s := &LRUStore{shards: make([]*shard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			capacity: per,
+			ll:       list.New(),
+			items:    make(map[string]*list.Element),
+			tags:     make(map[string]map[string]struct{}),
+		}
+	}
+	return s
+}
+
+func (s *LRUStore) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *LRUStore) Get(key string) (*Response, bool) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	el, ok := sh.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !e.rsp.Expires.IsZero() && time.Now().After(e.rsp.Expires) {
+		sh.remove(el)
+		return nil, false
+	}
+	sh.ll.MoveToFront(el)
+	return e.rsp, true
+}
+
+func (s *LRUStore) Set(key string, rsp *Response) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if el, ok := sh.items[key]; ok {
+		sh.remove(el)
+	}
+	sh.items[key] = sh.ll.PushFront(&entry{key: key, rsp: rsp})
+	for _, tag := range rsp.Tags {
+		keys, ok := sh.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			sh.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	for sh.ll.Len() > sh.capacity {
+		sh.remove(sh.ll.Back())
+	}
+}
+
+func (s *LRUStore) Delete(key string) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	if el, ok := sh.items[key]; ok {
+		sh.remove(el)
+	}
+	sh.mu.Unlock()
+}
+
+func (s *LRUStore) DeleteTags(tags ...string) {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for _, tag := range tags {
+			for key := range sh.tags[tag] {
+				if el, ok := sh.items[key]; ok {
+					sh.remove(el)
+				}
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+//当前缓存的条目数
+func (s *LRUStore) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		n += sh.ll.Len()
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+//调用方持有锁
+func (sh *shard) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	sh.ll.Remove(el)
+	delete(sh.items, e.key)
+	for _, tag := range e.rsp.Tags {
+		if keys, ok := sh.tags[tag]; ok {
+			delete(keys, e.key)
+			if len(keys) == 0 {
+				delete(sh.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package httpcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUStore(t *testing.T) {
+	s := NewLRUStore(2, 1)
+	s.Set("a", &Response{Status: 200, Tags: []string{"goods"}})
+	s.Set("b", &Response{Status: 200})
+	s.Get("a")
+	s.Set("c", &Response{Status: 200, Tags: []string{"goods"}})
+	if _, ok := s.Get("b"); ok {
+		t.Fatal("least recently used entry not evicted")
+	}
+	s.DeleteTags("goods")
+	if s.Len() != 0 {
+		t.Fatalf("tagged entries not deleted, len %d", s.Len())
+	}
+	s.Set("d", &Response{Status: 200, Expires: time.Now().Add(-time.Second)})
+	if _, ok := s.Get("d"); ok {
+		t.Fatal("expired entry returned")
+	}
+}
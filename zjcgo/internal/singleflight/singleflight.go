@@ -0,0 +1,55 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+)
+
+/*
+	同一个key同时只执行一次，其余调用等着拿同一个结果，防止缓存击穿
+*/
+
+//执行的函数panic了，等待的调用拿到这个错误
+var ErrPanic = errors.New("singleflight: function panicked")
+
+type call[V any] struct {
+	wg  sync.WaitGroup
+	val V
+	err error
+}
+
+type Group[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*call[V]
+}
+
+//shared表示结果是不是别的调用执行出来的
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	if c, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call[V]{}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	normalReturn := false
+	defer func() {
+		if !normalReturn {
+			c.err = ErrPanic
+		}
+		c.wg.Done()
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+	}()
+	c.val, c.err = fn()
+	normalReturn = true
+	return c.val, c.err, false
+}
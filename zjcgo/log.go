@@ -2,6 +2,7 @@ package zjcgo
 
 import (
 	"fmt"
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
 	"io"
 	"net"
 	"net/http"
@@ -31,22 +32,33 @@ const (
 var DefaultWriter io.Writer = os.Stdout
 
 type LoggerConfig struct {
-	Formatter LoggerFormatter
-	out       io.Writer
-	IsColor   bool
+	Formatter LoggerFormatter //默认带颜色的文本，内置的还有CommonLogFormatter、CombinedLogFormatter、JSONLogFormatter
+	Out       io.Writer       //默认DefaultWriter
+	Logger    *zjcLog.Logger  //设置了就按info级别写到logger的输出里(控制台、all.log、info.log)，优先于Out
+	IsColor   bool            //写到DefaultWriter时自动开启
+	SkipPaths []string        //不记录的路径，比如/healthz
+	Skip      func(ctx *Context) bool
 }
 
 // 参数是配置结构体，返回值是一个string
 type LoggerFormatter func(params LogFormatterParams) string
 
 type LogFormatterParams struct {
-	Request    *http.Request
-	TimeStamp  time.Time
-	StatusCode int
-	Latency    time.Duration
-	ClientIP   net.IP
-	Method     string
-	Path       string
+	Request      *http.Request
+	TimeStamp    time.Time
+	StatusCode   int
+	Latency      time.Duration
+	ClientIP     net.IP
+	Method       string
+	Path         string
+	IsColor      bool
+	RequestSize  int64 //请求体大小，不知道的是0
+	ResponseSize int   //响应体大小
+	UserAgent    string
+	Referer      string
+	RequestID    string
+	Route        string //匹配上的路由模板
+	User         string //BasicAuth认证的用户
 }
 
 func (p *LogFormatterParams) StatusCodeColor() string {
@@ -65,20 +77,35 @@ func (p *LogFormatterParams) ResetColor() string {
 
 //日志书写的主函数
 func LoggerWithConfig(conf LoggerConfig, next HandlerFunc) HandlerFunc {
-	fmt.Sprintf("%#v", red)
 	formatter := conf.Formatter //配置结构体
 	if formatter == nil {
 		formatter = defaultLogFormatter //默认输出
 	}
-	out := conf.out
+	out := conf.Out
+	if conf.Logger != nil {
+		out = conf.Logger.Writer(zjcLog.LevelInfo)
+	}
 	if out == nil {
 		out = DefaultWriter
 		conf.IsColor = true
 	}
+	skip := make(map[string]struct{}, len(conf.SkipPaths))
+	for _, p := range conf.SkipPaths {
+		skip[p] = struct{}{}
+	}
 	return func(ctx *Context) {
+		if _, ok := skip[ctx.R.URL.Path]; ok {
+			next(ctx)
+			return
+		}
+		if conf.Skip != nil && conf.Skip(ctx) {
+			next(ctx)
+			return
+		}
 		//参数
 		param := LogFormatterParams{
 			Request: ctx.R,
+			IsColor: conf.IsColor,
 		}
 		// Start timer
 		start := time.Now()
@@ -93,6 +120,10 @@ func LoggerWithConfig(conf LoggerConfig, next HandlerFunc) HandlerFunc {
 		clientIP := net.ParseIP(ip)
 		method := ctx.R.Method
 		statusCode := ctx.StatusCode
+		if w, ok := ctx.W.(ResponseWriter); ok {
+			statusCode = w.Status()
+			param.ResponseSize = w.Size()
+		}
 
 		if raw != "" {
 			path = path + "?" + raw
@@ -104,7 +135,19 @@ func LoggerWithConfig(conf LoggerConfig, next HandlerFunc) HandlerFunc {
 		param.StatusCode = statusCode
 		param.Method = method
 		param.Path = path
-		fmt.Fprint(out, formatter(param))
+		if ctx.R.ContentLength > 0 {
+			param.RequestSize = ctx.R.ContentLength
+		}
+		param.UserAgent = ctx.R.UserAgent()
+		param.Referer = ctx.R.Referer()
+		param.RequestID = ctx.RequestID()
+		param.Route = ctx.FullPath()
+		param.User = ctx.GetString("user")
+		line := formatter(param)
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		_, _ = io.WriteString(out, line)
 	}
 }
 
@@ -113,11 +156,21 @@ func Logging(next HandlerFunc) HandlerFunc {
 }
 
 var defaultLogFormatter = func(params LogFormatterParams) string {
-	statusCodeColor := params.StatusCodeColor()
-	resetColor := params.ResetColor()
 	if params.Latency > time.Minute {
 		params.Latency = params.Latency.Truncate(time.Second)
 	}
+	if !params.IsColor {
+		return fmt.Sprintf("[zjcgo] | %v | %3d | %13v | %15s | %-7s %#v",
+			params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			params.StatusCode,
+			params.Latency,
+			params.ClientIP,
+			params.Method,
+			params.Path,
+		)
+	}
+	statusCodeColor := params.StatusCodeColor()
+	resetColor := params.ResetColor()
 	return fmt.Sprintf("%s [zjcgo] %s |%s %v %s| %s %3d %s |%s %13v %s| %15s  |%s %-7s %s %s %#v %s",
 		yellow, resetColor, blue, params.TimeStamp.Format("2006/01/02 - 15:04:05"), resetColor,
 		statusCodeColor, params.StatusCode, resetColor,
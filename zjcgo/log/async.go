@@ -0,0 +1,224 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	异步写日志，Print只把格式化好的日志放进有界的环形缓冲区，后台goroutine攒一批再写
+	l.SetAsync(log.AsyncConfig{BufferSize: 4096, Overflow: log.DropOldest})
+	程序退出前调用 l.Flush() 或者 l.CloseWriter()，engine.Shutdown会调用
+*/
+
+//缓冲区满了怎么办
+type OverflowPolicy int
+
+const (
+	Block      OverflowPolicy = iota //等后台写出去腾出位置
+	DropOldest                       //丢掉最早的
+	DropNewest                       //丢掉当前这条
+)
+
+func ParseOverflow(s string) (OverflowPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "block":
+		return Block, nil
+	case "drop-oldest", "drop_oldest":
+		return DropOldest, nil
+	case "drop-newest", "drop_newest":
+		return DropNewest, nil
+	}
+	return Block, errors.New("unknown log overflow policy: " + s)
+}
+
+type AsyncConfig struct {
+	BufferSize    int           //缓冲多少条，默认1024
+	BatchSize     int           //一次最多写多少条，默认64
+	FlushInterval time.Duration //>0时攒够BatchSize或者到时间才写，否则有日志就写
+	Overflow      OverflowPolicy
+}
+
+type AsyncWriter struct {
+	out      io.Writer
+	conf     AsyncConfig
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	ring     [][]byte
+	head     int
+	count    int
+	dropped  uint64
+	closed   bool
+	wake     chan struct{}
+	flushReq chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func NewAsyncWriter(out io.Writer, conf AsyncConfig) *AsyncWriter {
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1024
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 64
+	}
+	w := &AsyncWriter{
+		out:      out,
+		conf:     conf,
+		ring:     make([][]byte, conf.BufferSize),
+		wake:     make(chan struct{}, 1),
+		flushReq: make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.notFull = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+	w.mu.Lock()
+	if w.closed {
+		//下面的输出已经关了，不能再写进去(RotatingWriter会重新打开文件)，改写到标准错误
+		w.mu.Unlock()
+		return w.writeClosed(p)
+	}
+	size := len(w.ring)
+	for w.count == size {
+		switch w.conf.Overflow {
+		case DropNewest:
+			w.dropped++
+			w.mu.Unlock()
+			return len(p), nil
+		case DropOldest:
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % size
+			w.count--
+			w.dropped++
+		default:
+			w.notFull.Wait()
+			if w.closed {
+				w.mu.Unlock()
+				return w.writeClosed(p)
+			}
+		}
+	}
+	w.ring[(w.head+w.count)%size] = data
+	w.count++
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+//等缓冲区里已有的日志都写出去
+func (w *AsyncWriter) Flush() {
+	ch := make(chan struct{})
+	select {
+	case w.flushReq <- ch:
+		<-ch
+	case <-w.done:
+	}
+}
+
+//丢掉的日志条数
+func (w *AsyncWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+//写完缓冲区里的日志，停掉后台goroutine，再关闭下面的输出(标准输出除外)
+func (w *AsyncWriter) Close() error {
+	w.once.Do(func() {
+		//先标记关闭，之后的Write不再进缓冲区，后台把缓冲区写空再退出
+		w.mu.Lock()
+		w.closed = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+		close(w.stop)
+		<-w.done
+	})
+	if w.out == os.Stdout || w.out == os.Stderr {
+		return nil
+	}
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (w *AsyncWriter) writeClosed(p []byte) (int, error) {
+	if w.out == os.Stdout || w.out == os.Stderr {
+		return w.out.Write(p)
+	}
+	return os.Stderr.Write(p)
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	var tick <-chan time.Time
+	if w.conf.FlushInterval > 0 {
+		ticker := time.NewTicker(w.conf.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-w.wake:
+			if tick == nil || w.pending() >= w.conf.BatchSize {
+				w.drain()
+			}
+		case <-tick:
+			w.drain()
+		case ch := <-w.flushReq:
+			w.drain()
+			close(ch)
+		case <-w.stop:
+			w.drain()
+			return
+		}
+	}
+}
+
+func (w *AsyncWriter) pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+//一批一批写，直到写空
+func (w *AsyncWriter) drain() {
+	var batch bytes.Buffer
+	for {
+		batch.Reset()
+		w.mu.Lock()
+		size := len(w.ring)
+		n := 0
+		for w.count > 0 && n < w.conf.BatchSize {
+			batch.Write(w.ring[w.head])
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % size
+			w.count--
+			n++
+		}
+		if n > 0 {
+			w.notFull.Broadcast()
+		}
+		w.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		_, _ = w.out.Write(batch.Bytes())
+	}
+}
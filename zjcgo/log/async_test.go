@@ -0,0 +1,91 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//写的时候卡住，用来把缓冲区填满
+type blockingWriter struct {
+	mu      sync.Mutex
+	release chan struct{}
+	buf     strings.Builder
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	w := NewAsyncWriter(out, AsyncConfig{BufferSize: 4, BatchSize: 2})
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("x\n"))
+	}
+	w.Flush()
+	if n := strings.Count(out.String(), "x"); n != 100 {
+		t.Fatalf("wrote %d lines, want 100", n)
+	}
+	w.Close()
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	for _, policy := range []OverflowPolicy{DropOldest, DropNewest} {
+		out := &blockingWriter{release: make(chan struct{})}
+		w := NewAsyncWriter(out, AsyncConfig{BufferSize: 2, BatchSize: 1, Overflow: policy})
+		//第一条被后台取走卡在Write里
+		w.Write([]byte("0"))
+		for w.pending() != 0 {
+		}
+		for _, s := range []string{"1", "2", "3"} {
+			w.Write([]byte(s))
+		}
+		if w.Dropped() != 1 {
+			t.Fatalf("policy %d dropped %d, want 1", policy, w.Dropped())
+		}
+		close(out.release)
+		w.Close()
+		want := "023"
+		if policy == DropNewest {
+			want = "012"
+		}
+		if out.String() != want {
+			t.Fatalf("policy %d wrote %q, want %q", policy, out.String(), want)
+		}
+	}
+}
+
+func TestAsyncWriterWriteAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "info.log")
+	rw, err := NewRotatingWriter(RotateConfig{Filename: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewAsyncWriter(rw, AsyncConfig{})
+	w.Write([]byte("before\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	//关闭以后的日志不能让RotatingWriter重新打开文件
+	w.Write([]byte("after\n"))
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("log file reopened after close: %v", err)
+	}
+}
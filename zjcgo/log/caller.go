@@ -0,0 +1,33 @@
+package log
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var pkgPath = reflect.TypeOf(Logger{}).PkgPath()
+
+//跳过Logger和slog适配器自己的调用，找到真正打日志的地方
+func caller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !internalFrame(f.Function) {
+			return filepath.Base(filepath.Dir(f.File)) + "/" + filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func internalFrame(function string) bool {
+	return strings.HasPrefix(function, pkgPath+".(*Logger).") ||
+		strings.HasPrefix(function, pkgPath+".(*SlogHandler).") ||
+		strings.HasPrefix(function, "log/slog.")
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+	logger放到context.Context里，跟着请求往下传
+	ctx = log.IntoContext(ctx, logger.With("request_id", id))
+	log.FromContext(ctx).Info("xxx")
+*/
+
+type loggerKey struct{}
+
+var (
+	defaultOnce   sync.Once
+	defaultLogger *Logger
+)
+
+func IntoContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+//context里没有logger时返回一个输出到控制台的默认logger
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	defaultOnce.Do(func() {
+		defaultLogger = Default()
+	})
+	return defaultLogger
+}
@@ -2,50 +2,125 @@ package log
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
+/*
+	json格式，每行一个对象，字段固定
+	{"ts":"2006-01-02T15:04:05.999999999Z07:00","level":"info","msg":"xxx","caller":"goodscenter/main.go:20","logger":"orm","trace_id":"...","fields":{...}}
+	msg或者字段是error的时候输出成 {"error":"...","chain":[...],"stack":"..."}
+*/
+
 type JsonFormatter struct {
-	TimeDisplay bool
+	TimeDisplay bool //兼容以前的配置，现在ts总是输出
+}
+
+type jsonEntry struct {
+	Ts      string         `json:"ts"`
+	Level   string         `json:"level"`
+	Msg     string         `json:"msg"`
+	Error   *jsonError     `json:"error,omitempty"`
+	Caller  string         `json:"caller,omitempty"`
+	Logger  string         `json:"logger,omitempty"`
+	TraceID string         `json:"trace_id,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+type jsonError struct {
+	Error string   `json:"error"`
+	Chain []string `json:"chain,omitempty"`
+	Stack string   `json:"stack,omitempty"`
 }
 
 func (f *JsonFormatter) Formatter(param *LoggingFormatterParam) string {
-	now := time.Now()
-	if param.LoggerFields == nil {
-		param.LoggerFields = make(Fields)
+	t := param.Time
+	if t.IsZero() {
+		t = time.Now()
 	}
-	if f.TimeDisplay {
-		timeNow := now.Format("2006/01/02 - 15:04:05")
-		param.LoggerFields["log_time"] = timeNow
+	entry := jsonEntry{
+		Ts:     t.Format(time.RFC3339Nano),
+		Level:  strings.ToLower(param.Level.Level()),
+		Caller: param.Caller,
+		Logger: param.LoggerName,
 	}
-	param.LoggerFields["msg"] = param.Msg
-	marshal, _ := json.Marshal(param.LoggerFields)
-	return fmt.Sprint(string(marshal))
+	if err, ok := param.Msg.(error); ok {
+		entry.Msg = err.Error()
+		entry.Error = newJsonError(err)
+	} else {
+		entry.Msg = fmt.Sprint(param.Msg)
+	}
+	//param.LoggerFields是logger共用的，复制一份再改
+	for k, v := range param.LoggerFields {
+		if k == "trace_id" {
+			entry.TraceID = fmt.Sprint(v)
+			continue
+		}
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]any, len(param.LoggerFields))
+		}
+		if err, ok := v.(error); ok {
+			entry.Fields[k] = newJsonError(err)
+		} else {
+			entry.Fields[k] = v
+		}
+	}
+	marshal, err := json.Marshal(entry)
+	if err != nil {
+		//字段里有不能序列化的值，转成字符串再来一次
+		for k, v := range entry.Fields {
+			entry.Fields[k] = fmt.Sprint(v)
+		}
+		marshal, _ = json.Marshal(entry)
+	}
+	return string(marshal)
 }
 
-func (f *JsonFormatter) LevelColor(level LoggerLevel) string {
-	switch level {
-	case LevelDebug:
-		return blue
-	case LevelInfo:
-		return green
-	case LevelError:
-		return red
-	default:
-		return cyan
+func newJsonError(err error) *jsonError {
+	return &jsonError{Error: err.Error(), Chain: errorChain(err), Stack: errorStack(err)}
+}
+
+//一层层Unwrap出来的错误信息，不包括最外层
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(e error)
+	walk = func(e error) {
+		switch x := e.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range x.Unwrap() {
+				chain = append(chain, inner.Error())
+				walk(inner)
+			}
+		default:
+			if inner := errors.Unwrap(e); inner != nil {
+				chain = append(chain, inner.Error())
+				walk(inner)
+			}
+		}
 	}
+	walk(err)
+	return chain
 }
 
-func (f *JsonFormatter) MsgColor(level LoggerLevel) string {
-	switch level {
-	case LevelDebug:
-		return ""
-	case LevelInfo:
-		return ""
-	case LevelError:
-		return red
-	default:
-		return cyan
+//错误链上第一个带调用栈的错误的调用栈
+func errorStack(err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if s, ok := e.(interface{ Stack() string }); ok && s.Stack() != "" {
+			return s.Stack()
+		}
+		if s, ok := stackTrace(e); ok && s != "" {
+			return s
+		}
 	}
+	return ""
+}
+
+func (f *JsonFormatter) LevelColor(level LoggerLevel) string {
+	return levelColor(level)
+}
+
+func (f *JsonFormatter) MsgColor(level LoggerLevel) string {
+	return msgColor(level)
 }
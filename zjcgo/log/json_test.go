@@ -0,0 +1,91 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJsonFormatter(t *testing.T) {
+	var buf strings.Builder
+	l := New()
+	l.Formatter = &JsonFormatter{}
+	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: &buf})
+	fields := Fields{"trace_id": "abc", "user_id": 1}
+	l = l.Named("orm").WithFields(fields)
+	base := errors.New("connection refused")
+	l.Error(fmt.Errorf("query goods: %w", base))
+	if len(fields) != 2 {
+		t.Fatalf("caller's fields mutated: %v", fields)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "error" || entry["logger"] != "orm" || entry["trace_id"] != "abc" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+	if !strings.HasPrefix(entry["caller"].(string), "log/json_test.go:") {
+		t.Fatalf("caller %v", entry["caller"])
+	}
+	chain := entry["error"].(map[string]any)["chain"].([]any)
+	if len(chain) != 1 || chain[0] != "connection refused" {
+		t.Fatalf("chain %v", chain)
+	}
+	if entry["fields"].(map[string]any)["user_id"] != float64(1) {
+		t.Fatalf("fields %v", entry["fields"])
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	out := (&LogfmtFormatter{}).Formatter(&LoggingFormatterParam{
+		Level:        LevelInfo,
+		Msg:          "user login",
+		LoggerFields: Fields{"user_id": 1, "name": "a=b"},
+	})
+	if !strings.Contains(out, ` level=info msg="user login" `) || !strings.HasSuffix(out, `name="a=b" user_id=1`) {
+		t.Fatalf("unexpected logfmt %q", out)
+	}
+}
+
+//仿github.com/pkg/errors，StackTrace()返回的类型实现了%+v
+type fakeStackTrace []string
+
+func (s fakeStackTrace) Format(f fmt.State, verb rune) {
+	for _, line := range s {
+		fmt.Fprintf(f, "\n%s", line)
+	}
+}
+
+type pkgError struct{ msg string }
+
+func (e *pkgError) Error() string { return e.msg }
+func (e *pkgError) StackTrace() fakeStackTrace {
+	return fakeStackTrace{"main.main", "\t/app/main.go:10"}
+}
+
+func TestJsonErrorStack(t *testing.T) {
+	for _, err := range []error{
+		fmt.Errorf("find goods: %w", WithStack(errors.New("timeout"))),
+		fmt.Errorf("find goods: %w", &pkgError{"timeout"}),
+	} {
+		var buf strings.Builder
+		l := New()
+		l.Formatter = &JsonFormatter{}
+		l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: &buf})
+		l.Error(err)
+		var entry struct {
+			Error struct {
+				Stack string `json:"stack"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Error.Stack == "" {
+			t.Fatalf("empty stack for %v: %s", err, buf.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	运行时可以改的日志级别
+	logger.SetLevel(log.LevelDebug) 改整个logger的级别
+	logger.Named("orm").SetLevel(log.LevelTrace) 或者 logger.SetModuleLevel("orm", log.LevelTrace) 只改orm模块
+	orm.query没有单独设置时用orm的，orm也没设置用根logger的
+	Logger.Level字段只为兼容保留，SetLevel会同步它；直接给它赋值只对这一个logger生效，子logger不会跟着变，
+	logger.Level = log.LevelInfo 建议改成 logger.SetLevel(log.LevelInfo)，读用logger.GetLevel()
+*/
+
+//没有单独设置级别
+const levelUnset = -100
+
+type AtomicLevel struct {
+	v int32
+}
+
+func NewAtomicLevel(level LoggerLevel) *AtomicLevel {
+	return &AtomicLevel{v: int32(level)}
+}
+
+func (a *AtomicLevel) Level() LoggerLevel {
+	return LoggerLevel(atomic.LoadInt32(&a.v))
+}
+
+func (a *AtomicLevel) SetLevel(level LoggerLevel) {
+	atomic.StoreInt32(&a.v, int32(level))
+}
+
+func (a *AtomicLevel) get() (LoggerLevel, bool) {
+	v := atomic.LoadInt32(&a.v)
+	return LoggerLevel(v), v != levelUnset
+}
+
+//一个logger和它所有的子logger共用
+type levelTree struct {
+	root    *AtomicLevel
+	mu      sync.Mutex
+	modules map[string]*AtomicLevel
+}
+
+func newLevelTree(level LoggerLevel) *levelTree {
+	return &levelTree{root: NewAtomicLevel(level), modules: make(map[string]*AtomicLevel)}
+}
+
+func (t *levelTree) module(name string) *AtomicLevel {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a, ok := t.modules[name]
+	if !ok {
+		a = NewAtomicLevel(levelUnset)
+		t.modules[name] = a
+	}
+	return a
+}
+
+//orm.query -> [orm.query, orm]
+func (t *levelTree) chain(name string) []*AtomicLevel {
+	var chain []*AtomicLevel
+	for name != "" {
+		chain = append(chain, t.module(name))
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return chain
+}
+
+//当前生效的级别
+func (l *Logger) GetLevel() LoggerLevel {
+	if l.levels == nil {
+		return l.Level
+	}
+	//兼容直接给Level字段赋值的老写法
+	if l.Level != l.syncedLevel {
+		return l.Level
+	}
+	for _, a := range l.chain {
+		if level, ok := a.get(); ok {
+			return level
+		}
+	}
+	return l.levels.root.Level()
+}
+
+//带名字的logger改的是这个模块的级别，否则改的是根级别，子logger都会跟着变
+func (l *Logger) SetLevel(level LoggerLevel) {
+	l.Level, l.syncedLevel = level, level
+	if l.Name != "" {
+		l.levels.module(l.Name).SetLevel(level)
+		return
+	}
+	l.levels.root.SetLevel(level)
+}
+
+func (l *Logger) SetModuleLevel(name string, level LoggerLevel) {
+	l.levels.module(name).SetLevel(level)
+}
+
+//去掉模块单独设置的级别，回到跟随上一级
+func (l *Logger) ResetModuleLevel(name string) {
+	l.levels.module(name).SetLevel(levelUnset)
+}
+
+func (l *Logger) SetRootLevel(level LoggerLevel) {
+	l.levels.root.SetLevel(level)
+}
+
+//根级别
+func (l *Logger) RootLevel() LoggerLevel {
+	return l.levels.root.Level()
+}
+
+//单独设置过级别的模块
+func (l *Logger) ModuleLevels() map[string]LoggerLevel {
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+	levels := make(map[string]LoggerLevel)
+	for name, a := range l.levels.modules {
+		if level, ok := a.get(); ok {
+			levels[name] = level
+		}
+	}
+	return levels
+}
+
+//模块名排好序，输出用
+func (l *Logger) ModuleNames() []string {
+	levels := l.ModuleLevels()
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
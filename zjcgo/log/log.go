@@ -2,9 +2,7 @@ package log
 
 import (
 	"fmt"
-	"github.com/zhengjingcheng/zjcgo/msstrings"
 	"io"
-	"log"
 	"os"
 	"path"
 	"strings"
@@ -34,20 +32,31 @@ type LoggerLevel int
 //添加其他字段
 type Fields map[string]any
 
-//log级别
+//log级别，从低到高
 const (
-	LevelDebug LoggerLevel = iota
+	LevelTrace LoggerLevel = iota
+	LevelDebug
 	LevelInfo
+	LevelWarn
 	LevelError
+	LevelFatal //打印完退出程序
+	LevelPanic //打印完panic
 )
 
 type Logger struct {
-	Level        LoggerLevel    //日志级别
+	Level        LoggerLevel    //Deprecated: 用SetLevel和GetLevel，直接赋值只对当前logger生效
+	syncedLevel  LoggerLevel    //SetLevel最后同步给Level的值，和Level不一样说明Level被直接改过
+	levels       *levelTree     //日志级别，子logger共用，可以运行时修改
+	chain        []*AtomicLevel //带名字的logger从自己到上级模块的级别
 	Outs         []LoggerWriter //输出流数组，因为可能不只是输出到控制台，所以开一个数组
 	Formatter    LoggingFormatter
-	LoggerFields Fields //其他字段
-	logPath      string //日志文件保存路径
-	LogFileSize  int64  //M为单位
+	Name         string         //logger的名字，一般是模块名
+	LoggerFields Fields         //其他字段
+	logPath      string         //日志文件保存路径
+	LogFileSize  int64          //单个日志文件的最大字节数，默认100M
+	Rotate       RotateConfig   //SetLogPath创建文件时用的切割配置，Filename和MaxSize不用填
+	async        *AsyncConfig   //开了异步，后面加的文件输出也包一层
+	sampling     *samplerHolder //日志采样，子logger共用
 }
 
 //日志输出方式
@@ -56,27 +65,36 @@ type LoggerWriter struct {
 	Out   io.Writer
 }
 
-//格式化日志文件输出
-type LoggerFormatter struct {
-	Color        bool //是不是带颜色
-	Level        LoggerLevel
-	loggerFields Fields
-}
-
 type LoggingFormatter interface {
 	Formatter(param *LoggingFormatterParam) string
 }
 
+//按名字取内置的格式 text、json、logfmt
+func NewFormatter(name string) (LoggingFormatter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "text":
+		return &TextFormatter{}, nil
+	case "json":
+		return &JsonFormatter{}, nil
+	case "logfmt":
+		return &LogfmtFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format: %q", name)
+}
+
 type LoggingFormatterParam struct {
 	Color        bool
 	Level        LoggerLevel
 	Msg          any
-	LoggerFields Fields
+	LoggerFields Fields //格式化的时候不要改它，和logger共用
+	Time         time.Time
+	Caller       string //调用打日志的位置 dir/file.go:line
+	LoggerName   string
 }
 
 //新建一个日志
 func New() *Logger {
-	return &Logger{}
+	return &Logger{levels: newLevelTree(LevelTrace), sampling: &samplerHolder{}}
 }
 
 //初始化
@@ -84,12 +102,16 @@ func Default() *Logger {
 	logger := New()
 	out := LoggerWriter{Out: os.Stdout}    //标准输出
 	logger.Outs = append(logger.Outs, out) //添加输出方式
-	logger.Level = LevelDebug
+	logger.SetLevel(LevelDebug)
 	logger.Formatter = &TextFormatter{}
 	return logger
 }
 
-//三级日志 参数:任意格式数据
+//各级日志 参数:任意格式数据
+func (l *Logger) Trace(msg any) {
+	l.Print(LevelTrace, msg)
+}
+
 func (l *Logger) Info(msg any) {
 	l.Print(LevelInfo, msg)
 	//l.Error(msg)
@@ -101,46 +123,176 @@ func (l *Logger) Debug(msg any) {
 	//l.Error(msg)
 }
 
+func (l *Logger) Warn(msg any) {
+	l.Print(LevelWarn, msg)
+}
+
 func (l *Logger) Error(msg any) {
 	l.Print(LevelError, msg)
 }
 
+//打印完退出程序
+func (l *Logger) Fatal(msg any) {
+	l.Print(LevelFatal, msg)
+	//异步的日志先写出去再退出
+	l.Flush()
+	os.Exit(1)
+}
+
+//打印完panic
+func (l *Logger) Panic(msg any) {
+	l.Print(LevelPanic, msg)
+	l.Flush()
+	panic(msg)
+}
+
+//格式化的版本 l.Infof("user %s login", name)
+func (l *Logger) Tracef(format string, args ...any) {
+	l.Print(LevelTrace, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Debugf(format string, args ...any) {
+	l.Print(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Infof(format string, args ...any) {
+	l.Print(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Warnf(format string, args ...any) {
+	l.Print(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Errorf(format string, args ...any) {
+	l.Print(LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.Fatal(fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Panicf(format string, args ...any) {
+	l.Panic(fmt.Sprintf(format, args...))
+}
+
+//返回带上这些字段的子logger，和原来的字段合并，同名的以新的为准，原logger不受影响
 func (l *Logger) WithFields(fields Fields) *Logger {
+	child := l.clone()
+	merged := make(Fields, len(l.LoggerFields)+len(fields))
+	for k, v := range l.LoggerFields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	child.LoggerFields = merged
+	return child
+}
+
+//l.With("user_id", 1, "path", "/login")，key不是字符串的转成字符串，落单的value记在!BADKEY下
+func (l *Logger) With(keyValues ...any) *Logger {
+	fields := make(Fields, len(keyValues)/2)
+	for i := 0; i < len(keyValues); i += 2 {
+		if i+1 == len(keyValues) {
+			fields["!BADKEY"] = keyValues[i]
+			break
+		}
+		key, ok := keyValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyValues[i])
+		}
+		fields[key] = keyValues[i+1]
+	}
+	return l.WithFields(fields)
+}
+
+//带名字的子logger，名字用.连起来 orm.query
+func (l *Logger) Named(name string) *Logger {
+	child := l.clone()
+	if l.Name != "" {
+		name = l.Name + "." + name
+	}
+	child.Name = name
+	child.chain = child.levels.chain(name)
+	return child
+}
+
+//子logger和父logger共用输出的writer，Outs各自一份，之后父logger再加输出或者开异步不影响子logger
+func (l *Logger) clone() *Logger {
 	return &Logger{
-		Formatter:    l.Formatter,
-		Outs:         l.Outs,
 		Level:        l.Level,
-		LoggerFields: fields, //相当于在原来的基础上增加了一个
+		syncedLevel:  l.syncedLevel,
+		levels:       l.levels,
+		chain:        l.chain,
+		Outs:         append([]LoggerWriter(nil), l.Outs...),
+		Formatter:    l.Formatter,
+		Name:         l.Name,
+		LoggerFields: l.LoggerFields,
+		logPath:      l.logPath,
+		LogFileSize:  l.LogFileSize,
+		Rotate:       l.Rotate,
+		async:        l.async,
+		sampling:     l.sampling,
 	}
 }
 
 func (l *Logger) Print(level LoggerLevel, msg any) {
-	if l.Level > level {
+	if l.GetLevel() > level {
 		//级别不满足 不打印日志
 		return
 	}
+	if s := l.sampling.load(); s != nil && !s.allow(level, msg) {
+		return
+	}
+	l.output(level, msg)
+}
+
+//不经过级别和采样判断，直接输出
+func (l *Logger) output(level LoggerLevel, msg any) {
 	param := &LoggingFormatterParam{
 		Level:        level,
 		Msg:          msg,
 		LoggerFields: l.LoggerFields,
+		Time:         time.Now(),
+		Caller:       caller(),
+		LoggerName:   l.Name,
 	}
 
 	for _, out := range l.Outs {
 		if out.Out == os.Stdout {
 			param.Color = true
 			l.print(param, out)
-		}
-		if out.Level == -1 || out.Level == level {
+		} else if out.Level == -1 || out.Level == level {
 			param.Color = false
 			l.print(param, out)
-			//
-			l.CheckFileSize(out)
 		}
 
 	}
 
 }
 
+//不经过Formatter，把内容原样写到这个级别的输出里，访问日志这种自己有格式的用
+func (l *Logger) Writer(level LoggerLevel) io.Writer {
+	return &levelWriter{logger: l, level: level}
+}
+
+type levelWriter struct {
+	logger *Logger
+	level  LoggerLevel
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if w.logger.GetLevel() > w.level {
+		return len(p), nil
+	}
+	for _, out := range w.logger.Outs {
+		if out.Out == os.Stdout || out.Level == -1 || out.Level == w.level {
+			_, _ = out.Out.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
 func (l *Logger) print(param *LoggingFormatterParam, out LoggerWriter) {
 	formatter := l.Formatter.Formatter(param)
 	fmt.Fprintln(out.Out, formatter)
@@ -148,120 +300,183 @@ func (l *Logger) print(param *LoggingFormatterParam, out LoggerWriter) {
 func (l *Logger) SetLogPath(logPath string) {
 	l.logPath = logPath
 	//写入文件
-	all, err := FileWriter(path.Join(l.logPath, "all.log"))
+	all, err := l.rotatingWriter("all.log")
 	if err != nil {
 		panic(err)
 	}
-	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: all})
-	debug, err := FileWriter(path.Join(l.logPath, "debug.log"))
-	if err != nil {
-		panic(err)
-	}
-	l.Outs = append(l.Outs, LoggerWriter{Level: LevelDebug, Out: debug})
-	info, err := FileWriter(path.Join(l.logPath, "info.log"))
-	if err != nil {
-		panic(err)
-	}
-	l.Outs = append(l.Outs, LoggerWriter{Level: LevelInfo, Out: info})
-	logError, err := FileWriter(path.Join(l.logPath, "error.log"))
-	if err != nil {
-		panic(err)
-	}
-	l.Outs = append(l.Outs, LoggerWriter{Level: LevelError, Out: logError})
-}
-
-func (f *LoggerFormatter) formatter(msg any, fields Fields) string {
-	now := time.Now()
-	if f.Color {
-		//要带颜色  error的颜色 为红色 info为绿色 debug为蓝色
-		levelColor := f.LevelColor()
-		msgColor := f.MsgColor()
-		return fmt.Sprintf("%s [zjcgo] %s %s%v%s | level= %s %s %s | msg=%s %#v %s %#v\n",
-			yellow, reset, blue, now.Format("2006/01/02 - 15:04:05"), reset,
-			levelColor, f.Level.Level(), reset, msgColor, msg, reset, fields,
-		)
+	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: l.asyncWriter(all)})
+	//每个级别一个文件 trace.log debug.log ... panic.log
+	for level := LevelTrace; level <= LevelPanic; level++ {
+		w, err := l.rotatingWriter(strings.ToLower(level.Level()) + ".log")
+		if err != nil {
+			panic(err)
+		}
+		l.Outs = append(l.Outs, LoggerWriter{Level: level, Out: l.asyncWriter(w)})
 	}
-	return fmt.Sprintf("[zjcgo] %v | level = %s | msg = %#v \n",
-		now.Format("2006/01/02 - 15:04:05"),
-		f.Level.Level(), msg, fields,
-	)
 }
+
 func (level LoggerLevel) Level() string {
 	switch level {
+	case LevelTrace:
+		return "TRACE"
 	case LevelDebug:
 		return "DEBUG"
 	case LevelInfo:
 		return "INFO"
+	case LevelWarn:
+		return "WARN"
 	case LevelError:
 		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	case LevelPanic:
+		return "PANIC"
 	default:
 		return ""
 	}
 }
 
-func (f *LoggerFormatter) LevelColor() string {
-	switch f.Level {
-	case LevelDebug:
-		return blue
-	case LevelInfo:
-		return green
-	case LevelError:
-		return red
-	default:
-		return cyan
+func (level LoggerLevel) String() string {
+	return level.Level()
+}
+
+//配置文件里的级别转成LoggerLevel，不区分大小写，warning等同warn
+func ParseLevel(level string) (LoggerLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	case "panic":
+		return LevelPanic, nil
 	}
+	return LevelInfo, fmt.Errorf("unknown log level: %q", level)
 }
 
-func (f *LoggerFormatter) MsgColor() string {
-	switch f.Level {
+//各级别的颜色
+func levelColor(level LoggerLevel) string {
+	switch level {
+	case LevelTrace:
+		return white
 	case LevelDebug:
 		return blue
 	case LevelInfo:
 		return green
+	case LevelWarn:
+		return yellow
 	case LevelError:
 		return red
+	case LevelFatal, LevelPanic:
+		return magenta
 	default:
 		return cyan
 	}
 }
 
-func FileWriter(name string) (io.Writer, error) {
-	w, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	return w, err
+//消息的颜色，warn以下不加颜色
+func msgColor(level LoggerLevel) string {
+	if level < LevelWarn {
+		return ""
+	}
+	return levelColor(level)
 }
 
-func (l *Logger) CloseWriter() {
+//文件输出改成异步写，控制台输出还是同步的
+func (l *Logger) SetAsync(conf AsyncConfig) {
+	l.async = &conf
+	//换一个新的切片，不改已有的元素
+	outs := make([]LoggerWriter, len(l.Outs))
+	for i, out := range l.Outs {
+		outs[i] = LoggerWriter{Level: out.Level, Out: l.asyncWriter(out.Out)}
+	}
+	l.Outs = outs
+}
+
+func (l *Logger) asyncWriter(w io.Writer) io.Writer {
+	if l.async == nil || w == os.Stdout || w == os.Stderr {
+		return w
+	}
+	if _, ok := w.(*AsyncWriter); ok {
+		return w
+	}
+	return NewAsyncWriter(w, *l.async)
+}
+
+//异步输出缓冲的日志全部写出去
+func (l *Logger) Flush() {
+	for _, out := range l.Outs {
+		if w, ok := out.Out.(*AsyncWriter); ok {
+			w.Flush()
+		}
+	}
+}
+
+//异步输出因为缓冲区满丢掉的日志条数
+func (l *Logger) Dropped() uint64 {
+	var n uint64
 	for _, out := range l.Outs {
-		file := out.Out.(*os.File)
-		if file != nil {
-			_ = file.Close()
+		if w, ok := out.Out.(*AsyncWriter); ok {
+			n += w.Dropped()
 		}
 	}
+	return n
+}
+
+func (l *Logger) rotatingWriter(name string) (*RotatingWriter, error) {
+	conf := l.Rotate
+	conf.Filename = path.Join(l.logPath, name)
+	conf.MaxSize = l.fileSize()
+	return NewRotatingWriter(conf)
+}
+
+func (l *Logger) fileSize() int64 {
+	if l.LogFileSize <= 0 {
+		//默认100M
+		return 100 << 20
+	}
+	return l.LogFileSize
+}
+
+func FileWriter(name string) (io.Writer, error) {
+	w, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	return w, err
 }
-func (l *Logger) CheckFileSize(out LoggerWriter) {
-	osFile := out.Out.(*os.File)
-	if osFile != nil {
-		stat, err := osFile.Stat()
+
+//添加一个输出，level为-1时所有级别都写
+//直接打开的文件(标准输出除外)换成同名的RotatingWriter，按LogFileSize和Rotate切割
+func (l *Logger) AddOutput(level LoggerLevel, w io.Writer) error {
+	if f, ok := w.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+		conf := l.Rotate
+		conf.Filename = f.Name()
+		conf.MaxSize = l.fileSize()
+		rw, err := NewRotatingWriter(conf)
 		if err != nil {
-			log.Println("logger checkFileSize error info :", err)
-			return
+			return err
 		}
-		size := stat.Size()
-		//这里要检查大小，如果满足条件 就重新创建文件，并且更换logger中的输出
-		if l.LogFileSize <= 0 {
-			//默认100M
-			l.LogFileSize = 100 << 20
+		_ = f.Close()
+		w = rw
+	}
+	l.Outs = append(l.Outs, LoggerWriter{Level: level, Out: l.asyncWriter(w)})
+	return nil
+}
+
+func (l *Logger) CloseWriter() {
+	//先把采样的汇总打出来
+	l.StopSampling()
+	for _, out := range l.Outs {
+		if out.Out == os.Stdout || out.Out == os.Stderr {
+			continue
 		}
-		if size >= l.LogFileSize {
-			_, fileName := path.Split(osFile.Name())
-			name := fileName[0:strings.Index(fileName, ".")]
-			w, err := FileWriter(path.Join(l.logPath, msstrings.JoinStrings(name, ".", time.Now().UnixMilli(), ".log")))
-			if err != nil {
-				log.Println("logger checkFileSize error info :", err)
-				return
-			}
-			out.Out = w
+		if closer, ok := out.Out.(io.Closer); ok {
+			_ = closer.Close()
 		}
 	}
-
 }
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]LoggerLevel{"trace": LevelTrace, "Warning": LevelWarn, " ERROR ": LevelError, "panic": LevelPanic} {
+		got, err := ParseLevel(s)
+		if err != nil || got != want {
+			t.Fatalf("ParseLevel(%q) = %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+}
+
+func TestWithFieldsMerges(t *testing.T) {
+	parent := Default().WithFields(Fields{"a": 1})
+	child := parent.With("b", 2, "a", 3)
+	if len(parent.LoggerFields) != 1 || parent.LoggerFields["a"] != 1 {
+		t.Fatalf("parent changed: %v", parent.LoggerFields)
+	}
+	if child.LoggerFields["a"] != 3 || child.LoggerFields["b"] != 2 {
+		t.Fatalf("child fields %v", child.LoggerFields)
+	}
+	if FromContext(IntoContext(context.Background(), child)) != child {
+		t.Fatal("logger not stored in context")
+	}
+}
+
+//父logger开异步、加输出，不能改到已经创建的子logger
+func TestChildOutsNotShared(t *testing.T) {
+	var buf strings.Builder
+	l := New()
+	l.Formatter = &TextFormatter{}
+	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: &buf})
+	child := l.With("module", "orm")
+	l.SetAsync(AsyncConfig{})
+	defer l.CloseWriter()
+	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: &strings.Builder{}})
+	if len(child.Outs) != 1 || child.Outs[0].Out != &buf {
+		t.Fatalf("child outputs changed: %+v", child.Outs)
+	}
+	if _, ok := l.Outs[0].Out.(*AsyncWriter); !ok {
+		t.Fatalf("parent output %T, want *AsyncWriter", l.Outs[0].Out)
+	}
+}
+
+//老代码直接给Level字段赋值还要生效
+func TestDeprecatedLevelField(t *testing.T) {
+	l := New()
+	l.SetLevel(LevelInfo)
+	if l.Level != LevelInfo {
+		t.Fatalf("Level field not synced: %v", l.Level)
+	}
+	child := l.Named("orm")
+	l.Level = LevelError
+	if l.GetLevel() != LevelError {
+		t.Fatalf("assigned level ignored: %v", l.GetLevel())
+	}
+	if child.GetLevel() != LevelInfo {
+		t.Fatalf("child level %v, want the shared level", child.GetLevel())
+	}
+	//改回用SetLevel以后以共用的级别为准
+	l.SetLevel(LevelWarn)
+	if l.GetLevel() != LevelWarn || child.GetLevel() != LevelWarn {
+		t.Fatalf("root %v child %v", l.GetLevel(), child.GetLevel())
+	}
+}
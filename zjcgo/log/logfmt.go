@@ -0,0 +1,64 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	logfmt格式
+	ts=2006-01-02T15:04:05.999999999Z07:00 level=info msg="user login" caller=blog/main.go:20 user_id=1
+	字段按key排序，值里有空格、引号、等号的加引号
+*/
+
+type LogfmtFormatter struct {
+}
+
+func (f *LogfmtFormatter) Formatter(param *LoggingFormatterParam) string {
+	t := param.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var sb strings.Builder
+	writeLogfmt(&sb, "ts", t.Format(time.RFC3339Nano))
+	writeLogfmt(&sb, "level", strings.ToLower(param.Level.Level()))
+	writeLogfmt(&sb, "msg", fmt.Sprint(param.Msg))
+	if param.Caller != "" {
+		writeLogfmt(&sb, "caller", param.Caller)
+	}
+	if param.LoggerName != "" {
+		writeLogfmt(&sb, "logger", param.LoggerName)
+	}
+	keys := make([]string, 0, len(param.LoggerFields))
+	for k := range param.LoggerFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeLogfmt(&sb, k, fmt.Sprint(param.LoggerFields[k]))
+	}
+	return sb.String()
+}
+
+func writeLogfmt(sb *strings.Builder, key string, value string) {
+	if sb.Len() > 0 {
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(key)
+	sb.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " \"=\t\r\n") {
+		fmt.Fprintf(sb, "%q", value)
+		return
+	}
+	sb.WriteString(value)
+}
+
+func (f *LogfmtFormatter) LevelColor(level LoggerLevel) string {
+	return levelColor(level)
+}
+
+func (f *LogfmtFormatter) MsgColor(level LoggerLevel) string {
+	return msgColor(level)
+}
@@ -0,0 +1,349 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+	按大小或者按天/小时切割的日志文件
+	w, err := log.NewRotatingWriter(log.RotateConfig{Filename: "./log/info.log", MaxSize: 100 << 20, Rotation: log.RotateDaily, MaxBackups: 7, Compress: true})
+	切下来的文件叫 info-2006-01-02T15-04-05.000.log，开了Compress会在后台压成 .gz
+	按时间切的用文件所属时间段的开始时间命名，比如18号一整天的是 info-2006-01-18T00-00-00.000.log
+	收到SIGHUP会重新打开文件，配合外部的logrotate使用
+*/
+
+type Rotation int
+
+const (
+	RotateNone   Rotation = iota //只按大小切
+	RotateDaily                  //每天切
+	RotateHourly                 //每小时切
+)
+
+//配置文件里的 daily/hourly
+func ParseRotation(s string) (Rotation, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return RotateNone, nil
+	case "daily", "day":
+		return RotateDaily, nil
+	case "hourly", "hour":
+		return RotateHourly, nil
+	}
+	return RotateNone, errors.New("unknown log rotation: " + s)
+}
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type RotateConfig struct {
+	Filename   string
+	MaxSize    int64         //单个文件最大字节数，<=0不按大小切
+	Rotation   Rotation      //按时间切
+	MaxBackups int           //最多保留几个切下来的文件，<=0不限制
+	MaxAge     time.Duration //切下来的文件最多保留多久，<=0不限制
+	Compress   bool          //切下来的文件压缩成gzip
+}
+
+type RotatingWriter struct {
+	conf   RotateConfig
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period string //当前文件所属的时间段，变了就切
+	now    func() time.Time
+	millCh chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewRotatingWriter(conf RotateConfig) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		conf:   conf,
+		now:    time.Now,
+		millCh: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.millRun()
+	watchSighup(w)
+	return w, nil
+}
+
+func (w *RotatingWriter) Filename() string {
+	return w.conf.Filename
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	now := w.now()
+	if w.periodOf(now) != w.period {
+		if err := w.rotate(w.periodStart()); err != nil {
+			return 0, err
+		}
+	} else if w.conf.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.conf.MaxSize {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+//马上切一次
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate(w.now())
+}
+
+//关掉再按原来的文件名打开，文件被外部移走以后用
+func (w *RotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		_ = w.file.Close()
+		w.file = nil
+	}
+	return w.open()
+}
+
+func (w *RotatingWriter) Close() error {
+	unwatchSighup(w)
+	w.once.Do(func() {
+		close(w.done)
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+//以下调用方持有锁
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.conf.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.conf.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	//进程重启时，上一个时间段留下的文件也要切走
+	w.period = w.periodOf(info.ModTime())
+	if info.Size() == 0 {
+		w.period = w.periodOf(w.now())
+	}
+	return nil
+}
+
+//backup是切下来的文件名里的时间
+func (w *RotatingWriter) rotate(backup time.Time) error {
+	if w.file != nil {
+		_ = w.file.Close()
+		w.file = nil
+	}
+	if _, err := os.Stat(w.conf.Filename); err == nil {
+		if err := os.Rename(w.conf.Filename, w.backupName(backup)); err != nil {
+			return err
+		}
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *RotatingWriter) periodLayout() string {
+	switch w.conf.Rotation {
+	case RotateDaily:
+		return "2006-01-02"
+	case RotateHourly:
+		return "2006-01-02T15"
+	}
+	return ""
+}
+
+//只按大小切时layout是空的，总是返回空字符串
+func (w *RotatingWriter) periodOf(t time.Time) string {
+	return t.Format(w.periodLayout())
+}
+
+//当前文件所属时间段的开始时间
+func (w *RotatingWriter) periodStart() time.Time {
+	t, err := time.ParseInLocation(w.periodLayout(), w.period, time.Local)
+	if err != nil {
+		return w.now()
+	}
+	return t
+}
+
+func (w *RotatingWriter) prefixAndExt() (string, string) {
+	base := filepath.Base(w.conf.Filename)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
+
+func (w *RotatingWriter) backupName(t time.Time) string {
+	prefix, ext := w.prefixAndExt()
+	return filepath.Join(filepath.Dir(w.conf.Filename), prefix+t.Format(backupTimeFormat)+ext)
+}
+
+//后台做压缩和清理，不阻塞写日志
+func (w *RotatingWriter) millRun() {
+	for {
+		select {
+		case <-w.millCh:
+			w.mill()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	t    time.Time
+}
+
+func (w *RotatingWriter) mill() {
+	dir := filepath.Dir(w.conf.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	prefix, ext := w.prefixAndExt()
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(strings.TrimSuffix(ts, ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), t: t})
+	}
+	//新的在前面
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+	var keep []backupFile
+	for i, b := range backups {
+		expired := w.conf.MaxAge > 0 && time.Since(b.t) > w.conf.MaxAge
+		if (w.conf.MaxBackups > 0 && i >= w.conf.MaxBackups) || expired {
+			_ = os.Remove(b.path)
+			continue
+		}
+		keep = append(keep, b)
+	}
+	if !w.conf.Compress {
+		return
+	}
+	for _, b := range keep {
+		if !strings.HasSuffix(b.path, ".gz") {
+			if err := gzipFile(b.path); err != nil {
+				Default().Error("compress log file " + b.path + ": " + err.Error())
+			}
+		}
+	}
+}
+
+//压缩成 name.gz 再删掉原文件
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, name+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+//SIGHUP时重新打开所有的RotatingWriter
+var (
+	sighupMu      sync.Mutex
+	sighupWriters = make(map[*RotatingWriter]struct{})
+	sighupOnce    sync.Once
+)
+
+func watchSighup(w *RotatingWriter) {
+	sighupMu.Lock()
+	sighupWriters[w] = struct{}{}
+	sighupMu.Unlock()
+	sighupOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		go func() {
+			for range ch {
+				sighupMu.Lock()
+				for w := range sighupWriters {
+					if err := w.Reopen(); err != nil {
+						Default().Error("reopen log file " + w.conf.Filename + ": " + err.Error())
+					}
+				}
+				sighupMu.Unlock()
+			}
+		}()
+	})
+}
+
+func unwatchSighup(w *RotatingWriter) {
+	sighupMu.Lock()
+	delete(sighupWriters, w)
+	sighupMu.Unlock()
+}
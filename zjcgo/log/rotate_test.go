@@ -0,0 +1,177 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriterSize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotatingWriter(RotateConfig{Filename: filepath.Join(dir, "info.log"), MaxSize: 10, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("12345678\n")); err != nil {
+			t.Fatal(err)
+		}
+		//备份文件名精确到毫秒
+		time.Sleep(2 * time.Millisecond)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, _ := os.ReadDir(dir)
+		var gz, plain int
+		for _, e := range entries {
+			switch {
+			case strings.HasSuffix(e.Name(), ".log.gz"):
+				gz++
+			case e.Name() != "info.log":
+				plain++
+			}
+		}
+		if gz == 2 && plain == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("backups not compressed and trimmed: %d gz, %d plain", gz, plain)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "info.log"))
+	if string(data) != "12345678\n" {
+		t.Fatalf("current file %q", data)
+	}
+}
+
+func TestLoggerNonFileOutput(t *testing.T) {
+	var buf strings.Builder
+	l := New()
+	l.SetLevel(LevelWarn)
+	l.Formatter = &TextFormatter{}
+	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: &buf})
+	l.Infof("hidden %d", 1)
+	l.Warnf("shown %d", 2)
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "level=WARN") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	l.CloseWriter()
+}
+
+func TestRotatingWriterPeriod(t *testing.T) {
+	cases := []struct {
+		rotation Rotation
+		start    time.Time
+		next     time.Time
+		backup   string
+	}{
+		{RotateDaily, time.Date(2026, 10, 18, 23, 59, 0, 0, time.Local), time.Date(2026, 10, 19, 0, 1, 0, 0, time.Local), "info-2026-10-18T00-00-00.000.log"},
+		{RotateHourly, time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local), time.Date(2026, 10, 18, 10, 0, 5, 0, time.Local), "info-2026-10-18T09-00-00.000.log"},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		w, err := NewRotatingWriter(RotateConfig{Filename: filepath.Join(dir, "info.log"), Rotation: c.rotation})
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := c.start
+		w.now = func() time.Time { return now }
+		//按假时钟重新算当前时间段
+		if err := w.Reopen(); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("old\n"))
+		w.Write([]byte("old\n"))
+		now = c.next
+		w.Write([]byte("new\n"))
+		w.Close()
+		//切下来的文件用上一个时间段命名
+		data, err := os.ReadFile(filepath.Join(dir, c.backup))
+		if err != nil || string(data) != "old\nold\n" {
+			t.Fatalf("rotation %d backup %q: %v", c.rotation, data, err)
+		}
+		data, _ = os.ReadFile(filepath.Join(dir, "info.log"))
+		if string(data) != "new\n" {
+			t.Fatalf("rotation %d current file %q", c.rotation, data)
+		}
+	}
+}
+
+func TestRotatingWriterSighup(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "info.log")
+	w, err := NewRotatingWriter(RotateConfig{Filename: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("before\n"))
+	//模拟外部logrotate把文件移走
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(name); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("log file not reopened on SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Write([]byte("after\n"))
+	data, _ := os.ReadFile(name)
+	if string(data) != "after\n" {
+		t.Fatalf("reopened file %q", data)
+	}
+	data, _ = os.ReadFile(name + ".1")
+	if string(data) != "before\n" {
+		t.Fatalf("moved file %q", data)
+	}
+}
+
+//直接加进来的文件换成RotatingWriter，写日志的时候不再检查大小
+func TestAddOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.OpenFile(filepath.Join(dir, "app.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New()
+	l.Formatter = &TextFormatter{}
+	l.LogFileSize = 200
+	if err := l.AddOutput(-1, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.Outs[0].Out.(*RotatingWriter); !ok {
+		t.Fatalf("output %T, want *RotatingWriter", l.Outs[0].Out)
+	}
+	child := l.Named("orm")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				child.Info("rotate me")
+				l.Info("rotate me")
+			}
+		}()
+	}
+	wg.Wait()
+	l.CloseWriter()
+	entries, _ := os.ReadDir(dir)
+	if len(entries) < 2 {
+		t.Fatalf("file not rotated: %d files", len(entries))
+	}
+}
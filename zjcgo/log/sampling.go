@@ -0,0 +1,170 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	日志采样，同样的日志(级别+消息)在一个周期里先打First条，之后每Thereafter条打一条
+	被丢掉的每个周期结束时汇总成一条 "suppressed N messages: xxx"
+	Fatal和Panic默认不采样，要采样的话在Levels里单独配置
+	logger.SetSampling(log.SamplingConfig{Interval: time.Second, First: 100, Thereafter: 100,
+		Levels: map[log.LoggerLevel]log.SamplingRule{log.LevelWarn: {First: 10, Thereafter: 1000}}})
+*/
+
+type SamplingRule struct {
+	First      int //每个周期先打多少条，<=0不采样
+	Thereafter int //之后每多少条打一条，<=0之后的全丢
+}
+
+type SamplingConfig struct {
+	Interval   time.Duration //默认1秒
+	First      int
+	Thereafter int
+	Levels     map[LoggerLevel]SamplingRule //按级别单独设置，没有的用上面的
+}
+
+//一个logger和它所有的子logger共用，替换或者停止采样以后子logger马上生效
+type samplerHolder struct {
+	v atomic.Value //*sampler
+}
+
+func (h *samplerHolder) load() *sampler {
+	if h == nil {
+		return nil
+	}
+	s, _ := h.v.Load().(*sampler)
+	return s
+}
+
+func (h *samplerHolder) swap(s *sampler) *sampler {
+	old := h.load()
+	h.v.Store(s)
+	return old
+}
+
+type sampleKey struct {
+	level LoggerLevel
+	msg   string
+}
+
+type sampleCount struct {
+	n          int
+	suppressed int
+}
+
+type sampler struct {
+	conf    SamplingConfig
+	logger  *Logger
+	mu      sync.Mutex
+	counts  map[sampleKey]*sampleCount
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+	stopped int32
+}
+
+//开启采样，子logger共用，再次调用会替换原来的配置
+func (l *Logger) SetSampling(conf SamplingConfig) {
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second
+	}
+	s := &sampler{
+		conf:   conf,
+		logger: l,
+		counts: make(map[sampleKey]*sampleCount),
+		done:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	if l.sampling == nil {
+		l.sampling = &samplerHolder{}
+	}
+	if old := l.sampling.swap(s); old != nil {
+		old.stop()
+	}
+}
+
+//停止采样，没汇总的马上汇总
+func (l *Logger) StopSampling() {
+	if l.sampling == nil {
+		return
+	}
+	if old := l.sampling.swap((*sampler)(nil)); old != nil {
+		old.stop()
+	}
+}
+
+func (s *sampler) rule(level LoggerLevel) SamplingRule {
+	if rule, ok := s.conf.Levels[level]; ok {
+		return rule
+	}
+	if level >= LevelFatal {
+		return SamplingRule{}
+	}
+	return SamplingRule{First: s.conf.First, Thereafter: s.conf.Thereafter}
+}
+
+func (s *sampler) allow(level LoggerLevel, msg any) bool {
+	rule := s.rule(level)
+	//已经停了的不再计数，全部放行
+	if rule.First <= 0 || atomic.LoadInt32(&s.stopped) == 1 {
+		return true
+	}
+	key := sampleKey{level: level, msg: fmt.Sprint(msg)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counts[key]
+	if !ok {
+		c = &sampleCount{}
+		s.counts[key] = c
+	}
+	c.n++
+	if c.n <= rule.First {
+		return true
+	}
+	if rule.Thereafter > 0 && (c.n-rule.First)%rule.Thereafter == 0 {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+func (s *sampler) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.conf.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.done:
+			s.flush()
+			return
+		}
+	}
+}
+
+//开始新的周期，把上个周期丢掉的汇总打出来
+func (s *sampler) flush() {
+	s.mu.Lock()
+	counts := s.counts
+	s.counts = make(map[sampleKey]*sampleCount, len(counts))
+	s.mu.Unlock()
+	for key, c := range counts {
+		if c.suppressed > 0 {
+			s.logger.output(key.level, fmt.Sprintf("suppressed %d messages: %s", c.suppressed, key.msg))
+		}
+	}
+}
+
+func (s *sampler) stop() {
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.stopped, 1)
+		close(s.done)
+	})
+	s.wg.Wait()
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	l := New()
+	l.Outs = []LoggerWriter{{Level: -1, Out: out}}
+	l.Formatter = &TextFormatter{}
+	l.SetSampling(SamplingConfig{Interval: time.Hour, First: 2, Thereafter: 3,
+		Levels: map[LoggerLevel]SamplingRule{LevelWarn: {}}})
+	for i := 0; i < 10; i++ {
+		l.Error("boom")
+		l.Warn("careful")
+	}
+	l.Error("other")
+	l.StopSampling()
+	s := out.String()
+	//前2条，之后第5、8条
+	if n := strings.Count(s, "boom"); n != 5 {
+		t.Fatalf("boom logged %d times, want 5:\n%s", n, s)
+	}
+	if !strings.Contains(s, "suppressed 6 messages: boom") {
+		t.Fatalf("missing summary:\n%s", s)
+	}
+	if n := strings.Count(s, "careful"); n != 10 {
+		t.Fatalf("warn sampled: %d", n)
+	}
+	if n := strings.Count(s, "other"); n != 1 {
+		t.Fatalf("other logged %d times", n)
+	}
+}
+
+//子logger在替换、停止采样以后也要跟着变
+func TestSamplingSharedWithChildren(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+	l := New()
+	l.Outs = []LoggerWriter{{Level: -1, Out: out}}
+	l.Formatter = &TextFormatter{}
+	l.SetSampling(SamplingConfig{Interval: time.Hour, First: 1})
+	child := l.Named("orm")
+	l.SetSampling(SamplingConfig{Interval: time.Hour, First: 2})
+	for i := 0; i < 5; i++ {
+		child.Error("replaced")
+		child.Print(LevelPanic, "panic")
+	}
+	l.StopSampling()
+	for i := 0; i < 3; i++ {
+		child.Error("stopped")
+	}
+	s := out.String()
+	if n := strings.Count(s, "replaced"); n != 3 {
+		t.Fatalf("replaced logged %d times, want 2 and a summary:\n%s", n, s)
+	}
+	if n := strings.Count(s, "panic"); n != 5 {
+		t.Fatalf("panic level sampled: %d", n)
+	}
+	if n := strings.Count(s, "stopped"); n != 3 {
+		t.Fatalf("stopped sampler still drops: %d", n)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+/*
+	slog.Handler适配，用log/slog打日志的库也写到zjcgo的输出里，用同样的格式
+	slog.SetDefault(slog.New(log.NewSlogHandler(engine.Logger)))
+*/
+
+type SlogHandler struct {
+	logger *Logger
+	group  string //WithGroup加的前缀 a.b.
+}
+
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l}
+}
+
+//返回写到这个logger的*slog.Logger
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.GetLevel() <= fromSlogLevel(level)
+}
+
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	logger := h.logger
+	if r.NumAttrs() > 0 {
+		fields := make(Fields, r.NumAttrs())
+		r.Attrs(func(attr slog.Attr) bool {
+			addAttr(fields, h.group, attr)
+			return true
+		})
+		logger = logger.WithFields(fields)
+	}
+	logger.Print(fromSlogLevel(r.Level), r.Message)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(Fields, len(attrs))
+	for _, attr := range attrs {
+		addAttr(fields, h.group, attr)
+	}
+	return &SlogHandler{logger: h.logger.WithFields(fields), group: h.group}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, group: h.group + name + "."}
+}
+
+//分组的属性展开成 group.key
+func addAttr(fields Fields, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			addAttr(fields, prefix, a)
+		}
+		return
+	}
+	fields[prefix+attr.Key] = attr.Value.Any()
+}
+
+func fromSlogLevel(level slog.Level) LoggerLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelTrace
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	}
+	return LevelError
+}
@@ -0,0 +1,26 @@
+//go:build go1.21
+
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf strings.Builder
+	l := New()
+	l.Formatter = &TextFormatter{}
+	l.Outs = append(l.Outs, LoggerWriter{Level: -1, Out: &buf})
+	l.SetLevel(LevelInfo)
+	s := l.Slog().With("service", "goods").WithGroup("req")
+	s.Debug("hidden")
+	s.Warn("slow query", "ms", 120)
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "level=WARN") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if !strings.Contains(out, "service=goods") || !strings.Contains(out, "req.ms=120") {
+		t.Fatalf("attrs missing in %q", out)
+	}
+}
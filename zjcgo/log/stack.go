@@ -0,0 +1,64 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+/*
+	带调用栈的错误，JsonFormatter会把调用栈写到error.stack里
+	return log.WithStack(err)
+	实现了Stack() string或者StackTrace()(比如github.com/pkg/errors)的错误也能取到调用栈
+*/
+
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+//记录调用WithStack的位置，err为nil返回nil
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+func (e *stackError) Stack() string {
+	return formatStack(e.pcs)
+}
+
+//一帧两行：函数名，缩进的 文件:行号
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+//pkg/errors的StackTrace()返回的类型没法直接引用，用反射调用再按%+v格式化
+func stackTrace(err error) (string, bool) {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return "", false
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()), "\n"), true
+}
@@ -10,7 +10,10 @@ type TextFormatter struct {
 }
 
 func (f *TextFormatter) Formatter(param *LoggingFormatterParam) string {
-	now := time.Now()
+	now := param.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
 	var builderField strings.Builder
 	var fieldsDisplay = ""
 	if param.LoggerFields != nil {
@@ -26,7 +29,7 @@ func (f *TextFormatter) Formatter(param *LoggingFormatterParam) string {
 		}
 	}
 	var msgInfo = "\n msg"
-	if param.Level == LevelError {
+	if param.Level >= LevelError {
 		msgInfo = "\n Error Cause By:"
 	}
 	if param.Color {
@@ -45,27 +48,9 @@ func (f *TextFormatter) Formatter(param *LoggingFormatterParam) string {
 }
 
 func (f *TextFormatter) LevelColor(level LoggerLevel) string {
-	switch level {
-	case LevelDebug:
-		return blue
-	case LevelInfo:
-		return green
-	case LevelError:
-		return red
-	default:
-		return cyan
-	}
+	return levelColor(level)
 }
 
 func (f *TextFormatter) MsgColor(level LoggerLevel) string {
-	switch level {
-	case LevelDebug:
-		return ""
-	case LevelInfo:
-		return ""
-	case LevelError:
-		return red
-	default:
-		return cyan
-	}
+	return msgColor(level)
 }
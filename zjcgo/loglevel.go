@@ -0,0 +1,152 @@
+package zjcgo
+
+import (
+	"encoding/json"
+	"github.com/zhengjingcheng/zjcgo/config"
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	运行时修改日志级别，挂在需要认证的组下面
+	admin := engine.Group("admin")
+	admin.Use(accounts.BasicAuth)
+	admin.LogLevel(engine.Logger)
+	GET  /admin/loglevel                               查看
+	PUT  /admin/loglevel {"level":"debug"}              改根级别
+	PUT  /admin/loglevel {"module":"orm","level":"trace"} 改模块级别，level为空时去掉模块的设置
+*/
+
+type logLevelState struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+type logLevelRequest struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+func (r *router) LogLevel(logger *zjcLog.Logger, middlewareFunc ...MiddlewareFunc) {
+	handler := LogLevelHandler(logger)
+	r.Get("/loglevel", handler, middlewareFunc...)
+	r.Put("/loglevel", handler, middlewareFunc...)
+}
+
+func LogLevelHandler(logger *zjcLog.Logger) HandlerFunc {
+	return func(ctx *Context) {
+		if ctx.R.Method == http.MethodPut {
+			var req logLevelRequest
+			//body为空时从query里取 ?module=orm&level=debug
+			if err := json.NewDecoder(ctx.R.Body).Decode(&req); err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if req.Module == "" && req.Level == "" {
+				req.Module = ctx.GetQuery("module")
+				req.Level = ctx.GetQuery("level")
+			}
+			if err := setLogLevel(logger, req.Module, req.Level); err != nil {
+				ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			logger.Warnf("log level changed: module=%q level=%q", req.Module, req.Level)
+		}
+		state := logLevelState{
+			Level:   strings.ToLower(logger.RootLevel().Level()),
+			Modules: make(map[string]string),
+		}
+		for name, level := range logger.ModuleLevels() {
+			state.Modules[name] = strings.ToLower(level.Level())
+		}
+		ctx.JSON(http.StatusOK, state)
+	}
+}
+
+func setLogLevel(logger *zjcLog.Logger, module string, level string) error {
+	if module != "" && level == "" {
+		logger.ResetModuleLevel(module)
+		return nil
+	}
+	l, err := zjcLog.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if module == "" {
+		logger.SetRootLevel(l)
+	} else {
+		logger.SetModuleLevel(module, l)
+	}
+	return nil
+}
+
+//[log] level="info"
+//[log.modules] orm="debug"
+//记住配置设置过什么，重新加载时配置里去掉的恢复原样，接口里临时改的模块不动
+type configLevels struct {
+	logger  *zjcLog.Logger
+	root    zjcLog.LoggerLevel //没有配置level时的根级别
+	mu      sync.Mutex
+	modules map[string]struct{}
+}
+
+func newConfigLevels(logger *zjcLog.Logger) *configLevels {
+	return &configLevels{logger: logger, root: logger.RootLevel(), modules: make(map[string]struct{})}
+}
+
+func (c *configLevels) apply(logConf map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if level, ok := logConf["level"].(string); ok {
+		if err := setLogLevel(c.logger, "", level); err != nil {
+			c.logger.Error(err.Error())
+		}
+	} else {
+		c.logger.SetRootLevel(c.root)
+	}
+	modules, _ := logConf["modules"].(map[string]any)
+	for name := range c.modules {
+		if _, ok := modules[name]; !ok {
+			c.logger.ResetModuleLevel(name)
+			delete(c.modules, name)
+		}
+	}
+	for name, v := range modules {
+		level, _ := v.(string)
+		if err := setLogLevel(c.logger, name, level); err != nil {
+			c.logger.Error(err.Error())
+			continue
+		}
+		c.modules[name] = struct{}{}
+	}
+}
+
+//[log.sampling]里的子表按级别名解析成单独的规则
+func samplingConfig(logger *zjcLog.Logger, section map[string]any) zjcLog.SamplingConfig {
+	conf := zjcLog.SamplingConfig{
+		Interval:   config.Duration(section, "interval", time.Second),
+		First:      int(config.Int(section, "first", 100)),
+		Thereafter: int(config.Int(section, "thereafter", 100)),
+		Levels:     make(map[zjcLog.LoggerLevel]zjcLog.SamplingRule),
+	}
+	for name, v := range section {
+		rule, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		level, err := zjcLog.ParseLevel(name)
+		if err != nil {
+			logger.Error(err.Error())
+			continue
+		}
+		conf.Levels[level] = zjcLog.SamplingRule{
+			First:      int(config.Int(rule, "first", 0)),
+			Thereafter: int(config.Int(rule, "thereafter", 0)),
+		}
+	}
+	return conf
+}
@@ -0,0 +1,59 @@
+package zjcgo
+
+import (
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	logger := zjcLog.New()
+	logger.SetLevel(zjcLog.LevelInfo)
+	orm := logger.Named("orm")
+	engine := New()
+	admin := engine.Group("admin")
+	admin.LogLevel(logger)
+
+	do := func(method, body string) string {
+		r := httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d %s", method, body, w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	do(http.MethodPut, `{"module":"orm","level":"trace"}`)
+	if orm.GetLevel() != zjcLog.LevelTrace || logger.GetLevel() != zjcLog.LevelInfo {
+		t.Fatalf("module level not applied: orm=%v root=%v", orm.GetLevel(), logger.GetLevel())
+	}
+	do(http.MethodPut, `{"level":"warn"}`)
+	if got := do(http.MethodGet, ""); got != `{"level":"warn","modules":{"orm":"trace"}}` {
+		t.Fatalf("unexpected state %s", got)
+	}
+	do(http.MethodPut, `{"module":"orm"}`)
+	if orm.GetLevel() != zjcLog.LevelWarn {
+		t.Fatalf("reset module should follow root, got %v", orm.GetLevel())
+	}
+}
+
+//重新加载配置时，配置里去掉的级别要恢复
+func TestConfigLevelsReload(t *testing.T) {
+	logger := zjcLog.New()
+	logger.SetLevel(zjcLog.LevelDebug)
+	levels := newConfigLevels(logger)
+	levels.apply(map[string]any{"level": "warn", "modules": map[string]any{"orm": "trace", "rpc": "error"}})
+	//接口里临时改的模块不受配置影响
+	logger.SetModuleLevel("cache", zjcLog.LevelError)
+	levels.apply(map[string]any{"modules": map[string]any{"rpc": "info"}})
+	if logger.RootLevel() != zjcLog.LevelDebug {
+		t.Fatalf("root level %v, want debug after level removed", logger.RootLevel())
+	}
+	want := map[string]zjcLog.LoggerLevel{"rpc": zjcLog.LevelInfo, "cache": zjcLog.LevelError}
+	got := logger.ModuleLevels()
+	if len(got) != len(want) || got["rpc"] != want["rpc"] || got["cache"] != want["cache"] {
+		t.Fatalf("module levels %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package zjcgo
+
+import (
+	"github.com/zhengjingcheng/zjcgo/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	http请求指标，按路由模板（不是真实路径）、请求方法、状态码统计
+	没匹配上路由的404、405不经过中间件，不会统计
+*/
+
+type MetricsConfig struct {
+	Registry  *metrics.Registry //注册到哪里，默认 metrics.DefaultRegistry
+	Namespace string            //指标前缀，默认 zjcgo
+	Buckets   []float64         //延迟分桶，默认 metrics.DefBuckets
+}
+
+type httpMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.GaugeVec
+}
+
+//中间件每次请求都会重新包装，所以指标要提前创建好
+func MetricsWithConfig(conf MetricsConfig) MiddlewareFunc {
+	m := newHttpMetrics(conf)
+	return func(next HandlerFunc) HandlerFunc {
+		return m.handle(next)
+	}
+}
+
+var (
+	defaultMetrics     *httpMetrics
+	defaultMetricsOnce sync.Once
+)
+
+func Metrics(next HandlerFunc) HandlerFunc {
+	defaultMetricsOnce.Do(func() {
+		defaultMetrics = newHttpMetrics(MetricsConfig{})
+	})
+	return defaultMetrics.handle(next)
+}
+
+func newHttpMetrics(conf MetricsConfig) *httpMetrics {
+	registry := conf.Registry
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	namespace := conf.Namespace
+	if namespace == "" {
+		namespace = "zjcgo"
+	}
+	m := &httpMetrics{
+		requests: metrics.NewCounterVec(namespace+"_http_requests_total",
+			"Total number of HTTP requests.", "method", "route", "status"),
+		duration: metrics.NewHistogramVec(namespace+"_http_request_duration_seconds",
+			"HTTP request latency in seconds.", conf.Buckets, "method", "route"),
+		inFlight: metrics.NewGaugeVec(namespace+"_http_requests_in_flight",
+			"Number of HTTP requests currently being served.", "method"),
+	}
+	registry.Register(m.requests)
+	registry.Register(m.duration)
+	registry.Register(m.inFlight)
+	return m
+}
+
+func (m *httpMetrics) handle(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		method := ctx.R.Method
+		inFlight := m.inFlight.WithLabelValues(method)
+		inFlight.Inc()
+		start := time.Now()
+		defer func() {
+			inFlight.Dec()
+			//中间件只在匹配上路由后执行，这里一定有路由模板
+			route := ctx.FullPath()
+			status := ctx.StatusCode
+			if w, ok := ctx.W.(ResponseWriter); ok {
+				status = w.Status()
+			}
+			m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+			m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		}()
+		next(ctx)
+	}
+}
+
+//暴露 /metrics 接口，registry为空时使用默认的注册中心
+func (e *Engine) ExposeMetrics(path string, registry *metrics.Registry) {
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	g := e.Group(strings.Trim(path, "/"))
+	g.Get("/", func(ctx *Context) {
+		registry.ServeHTTP(ctx.W, ctx.R)
+	})
+}
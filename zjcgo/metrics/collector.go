@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"database/sql"
+)
+
+/*
+	常用组件的指标采集：协程池、数据库连接池
+*/
+
+//采集时回调取值的gauge
+type GaugeFunc struct {
+	name   string
+	help   string
+	labels []Label
+	fn     func() float64
+}
+
+func NewGaugeFunc(name, help string, labels []Label, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, labels: labels, fn: fn}
+}
+
+func (g *GaugeFunc) Collect() []*MetricFamily {
+	return []*MetricFamily{{
+		Name:    g.name,
+		Help:    g.help,
+		Type:    typeGauge,
+		Samples: []Sample{{Labels: g.labels, Value: g.fn()}},
+	}}
+}
+
+//zjcpool.Pool 满足这个接口
+type PoolStats interface {
+	Running() int
+	Idle() int
+	Cap() int
+}
+
+type poolCollector struct {
+	labels []Label
+	pool   PoolStats
+}
+
+//协程池的运行数、空闲数和容量
+func NewPoolCollector(name string, pool PoolStats) Collector {
+	return &poolCollector{labels: []Label{{Name: "pool", Value: name}}, pool: pool}
+}
+
+func (c *poolCollector) Collect() []*MetricFamily {
+	return []*MetricFamily{
+		gaugeFamily("zjcgo_pool_running_workers", "Number of running workers.", c.labels, float64(c.pool.Running())),
+		gaugeFamily("zjcgo_pool_idle_workers", "Number of idle workers.", c.labels, float64(c.pool.Idle())),
+		gaugeFamily("zjcgo_pool_capacity", "Capacity of the pool.", c.labels, float64(c.pool.Cap())),
+	}
+}
+
+//orm.ZjcDb 满足这个接口
+type DBStats interface {
+	Stats() sql.DBStats
+}
+
+type dbCollector struct {
+	labels []Label
+	db     DBStats
+}
+
+//数据库连接池的统计信息
+func NewDBCollector(name string, db DBStats) Collector {
+	return &dbCollector{labels: []Label{{Name: "db", Value: name}}, db: db}
+}
+
+func (c *dbCollector) Collect() []*MetricFamily {
+	s := c.db.Stats()
+	return []*MetricFamily{
+		gaugeFamily("zjcgo_db_max_open_connections", "Maximum number of open connections to the database.", c.labels, float64(s.MaxOpenConnections)),
+		gaugeFamily("zjcgo_db_open_connections", "The number of established connections both in use and idle.", c.labels, float64(s.OpenConnections)),
+		gaugeFamily("zjcgo_db_in_use_connections", "The number of connections currently in use.", c.labels, float64(s.InUse)),
+		gaugeFamily("zjcgo_db_idle_connections", "The number of idle connections.", c.labels, float64(s.Idle)),
+		counterFamily("zjcgo_db_wait_count_total", "The total number of connections waited for.", c.labels, float64(s.WaitCount)),
+		counterFamily("zjcgo_db_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", c.labels, s.WaitDuration.Seconds()),
+		counterFamily("zjcgo_db_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", c.labels, float64(s.MaxIdleClosed)),
+		counterFamily("zjcgo_db_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.", c.labels, float64(s.MaxIdleTimeClosed)),
+		counterFamily("zjcgo_db_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", c.labels, float64(s.MaxLifetimeClosed)),
+	}
+}
+
+func gaugeFamily(name, help string, labels []Label, v float64) *MetricFamily {
+	return &MetricFamily{Name: name, Help: help, Type: typeGauge, Samples: []Sample{{Labels: labels, Value: v}}}
+}
+
+func counterFamily(name, help string, labels []Label, v float64) *MetricFamily {
+	return &MetricFamily{Name: name, Help: help, Type: typeCounter, Samples: []Sample{{Labels: labels, Value: v}}}
+}
@@ -0,0 +1,286 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	指标子系统，支持 counter gauge histogram 三种类型，输出prometheus文本格式，不依赖外部服务
+*/
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+//默认的延迟分桶，单位秒
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//label值之间的分隔符
+const labelSep = "\xff"
+
+//float64的原子操作
+type atomicFloat struct {
+	bits uint64
+}
+
+func (f *atomicFloat) Add(v float64) {
+	for {
+		old := atomic.LoadUint64(&f.bits)
+		n := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&f.bits, old, n) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) Set(v float64) {
+	atomic.StoreUint64(&f.bits, math.Float64bits(v))
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.bits))
+}
+
+//计数器 只增不减
+type Counter struct {
+	v atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("counter cannot decrease")
+	}
+	c.v.Add(v)
+}
+
+func (c *Counter) Value() float64 {
+	return c.v.Load()
+}
+
+//仪表盘 可增可减
+type Gauge struct {
+	v atomicFloat
+}
+
+func (g *Gauge) Set(v float64) {
+	g.v.Set(v)
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.v.Add(v)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.v.Load()
+}
+
+//直方图 按分桶统计分布
+type Histogram struct {
+	upperBounds []float64
+	counts      []uint64 //每个桶自己的数量，最后一个是超出所有上限的，输出时再累加
+	sum         atomicFloat
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upperBounds: buckets,
+		counts:      make([]uint64, len(buckets)+1),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upperBounds, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	h.sum.Add(v)
+}
+
+//返回累计后的桶数量，总数和总和
+//总数就是累加到最后的值，不单独计数，保证和+Inf桶一致
+func (h *Histogram) snapshot() ([]uint64, uint64, float64) {
+	cumulative := make([]uint64, len(h.counts))
+	var total uint64
+	for i := range h.counts {
+		total += atomic.LoadUint64(&h.counts[i])
+		cumulative[i] = total
+	}
+	return cumulative[:len(h.upperBounds)], total, h.sum.Load()
+}
+
+//指标族，同名指标按label值区分
+type family struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+	mu         sync.RWMutex
+	children   map[string]any
+	newChild   func() any
+}
+
+func newFamily(name, help, typ string, labelNames []string, newChild func() any) *family {
+	if !validName(name) {
+		panic("metrics: invalid metric name " + name)
+	}
+	for _, l := range labelNames {
+		if !validName(l) || strings.HasPrefix(l, "__") {
+			panic("metrics: invalid label name " + l)
+		}
+	}
+	return &family{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		children:   make(map[string]any),
+		newChild:   newChild,
+	}
+}
+
+func (f *family) Name() string {
+	return f.name
+}
+
+func (f *family) child(values ...string) any {
+	if len(values) != len(f.labelNames) {
+		panic("metrics: label values count not match for " + f.name)
+	}
+	key := strings.Join(values, labelSep)
+	f.mu.RLock()
+	c, ok := f.children[key]
+	f.mu.RUnlock()
+	if ok {
+		return c
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok = f.children[key]; ok {
+		return c
+	}
+	c = f.newChild()
+	f.children[key] = c
+	return c
+}
+
+//按label值排序后的子指标，保证输出稳定
+func (f *family) sortedChildren() ([]string, []any) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	keys := make([]string, 0, len(f.children))
+	for k := range f.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		values[i] = f.children[k]
+	}
+	return keys, values
+}
+
+func (f *family) labels(key string) []Label {
+	if len(f.labelNames) == 0 {
+		return nil
+	}
+	values := strings.Split(key, labelSep)
+	labels := make([]Label, len(values))
+	for i, v := range values {
+		labels[i] = Label{Name: f.labelNames[i], Value: v}
+	}
+	return labels
+}
+
+func (f *family) Collect() []*MetricFamily {
+	keys, children := f.sortedChildren()
+	if len(keys) == 0 {
+		return nil
+	}
+	mf := &MetricFamily{Name: f.name, Help: f.help, Type: f.typ}
+	for i, key := range keys {
+		sample := Sample{Labels: f.labels(key)}
+		switch c := children[i].(type) {
+		case *Counter:
+			sample.Value = c.Value()
+		case *Gauge:
+			sample.Value = c.Value()
+		case *Histogram:
+			counts, count, sum := c.snapshot()
+			sample.Histogram = &HistogramSample{UpperBounds: c.upperBounds, Counts: counts, Count: count, Sum: sum}
+		}
+		mf.Samples = append(mf.Samples, sample)
+	}
+	return []*MetricFamily{mf}
+}
+
+type CounterVec struct {
+	*family
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{newFamily(name, help, typeCounter, labelNames, func() any { return &Counter{} })}
+}
+
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.child(values...).(*Counter)
+}
+
+type GaugeVec struct {
+	*family
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{newFamily(name, help, typeGauge, labelNames, func() any { return &Gauge{} })}
+}
+
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return v.child(values...).(*Gauge)
+}
+
+type HistogramVec struct {
+	*family
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+	return &HistogramVec{newFamily(name, help, typeHistogram, labelNames, func() any { return newHistogram(b) })}
+}
+
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.child(values...).(*Histogram)
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
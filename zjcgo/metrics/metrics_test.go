@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	requests := NewCounterVec("http_requests_total", "Total requests.", "method", "route")
+	latency := NewHistogramVec("http_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.Register(requests)
+	r.Register(latency)
+	r.Register(NewGaugeFunc("pool_capacity", "Capacity.", []Label{{Name: "pool", Value: "a\"b"}}, func() float64 { return 10 }))
+
+	requests.WithLabelValues("GET", "/user/:id").Add(2)
+	latency.WithLabelValues("/user/:id").Observe(0.05)
+	latency.WithLabelValues("/user/:id").Observe(0.5)
+	latency.WithLabelValues("/user/:id").Observe(5)
+
+	var sb strings.Builder
+	if err := r.WritePrometheus(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"# TYPE http_requests_total counter\n",
+		`http_requests_total{method="GET",route="/user/:id"} 2`,
+		`http_latency_seconds_bucket{route="/user/:id",le="0.1"} 1`,
+		`http_latency_seconds_bucket{route="/user/:id",le="1"} 2`,
+		`http_latency_seconds_bucket{route="/user/:id",le="+Inf"} 3`,
+		`http_latency_seconds_sum{route="/user/:id"} 5.55`,
+		`http_latency_seconds_count{route="/user/:id"} 3`,
+		`pool_capacity{pool="a\"b"} 10`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
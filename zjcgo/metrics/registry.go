@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type HistogramSample struct {
+	UpperBounds []float64
+	Counts      []uint64 //累计数量，和UpperBounds一一对应
+	Count       uint64
+	Sum         float64
+}
+
+type Sample struct {
+	Labels    []Label
+	Value     float64
+	Histogram *HistogramSample
+}
+
+//一组同名指标
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+//所有能被采集的指标都实现这个接口
+type Collector interface {
+	Collect() []*MetricFamily
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+//默认注册中心，框架内置的指标都注册在这里
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+func (r *Registry) Unregister(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.collectors {
+		if v == c {
+			r.collectors = append(r.collectors[:i], r.collectors[i+1:]...)
+			return
+		}
+	}
+}
+
+//采集所有指标，同名的指标合并到一起并按名字排序
+func (r *Registry) Gather() []*MetricFamily {
+	r.mu.RLock()
+	collectors := append([]Collector{}, r.collectors...)
+	r.mu.RUnlock()
+	byName := make(map[string]*MetricFamily)
+	for _, c := range collectors {
+		for _, mf := range c.Collect() {
+			if exist, ok := byName[mf.Name]; ok {
+				exist.Samples = append(exist.Samples, mf.Samples...)
+				continue
+			}
+			byName[mf.Name] = mf
+		}
+	}
+	families := make([]*MetricFamily, 0, len(byName))
+	for _, mf := range byName {
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
+
+//输出prometheus文本格式
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, mf := range r.Gather() {
+		writeFamily(bw, mf)
+	}
+	return bw.Flush()
+}
+
+//作为 /metrics 接口
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WritePrometheus(w)
+}
+
+func writeFamily(w *bufio.Writer, mf *MetricFamily) {
+	if mf.Help != "" {
+		w.WriteString("# HELP " + mf.Name + " " + escapeHelp(mf.Help) + "\n")
+	}
+	w.WriteString("# TYPE " + mf.Name + " " + mf.Type + "\n")
+	for _, s := range mf.Samples {
+		if s.Histogram == nil {
+			writeSample(w, mf.Name, s.Labels, s.Value)
+			continue
+		}
+		h := s.Histogram
+		for i, upper := range h.UpperBounds {
+			writeSample(w, mf.Name+"_bucket", withLabel(s.Labels, "le", formatFloat(upper)), float64(h.Counts[i]))
+		}
+		writeSample(w, mf.Name+"_bucket", withLabel(s.Labels, "le", "+Inf"), float64(h.Count))
+		writeSample(w, mf.Name+"_sum", s.Labels, h.Sum)
+		writeSample(w, mf.Name+"_count", s.Labels, float64(h.Count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels []Label, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.Name + `="` + escapeLabel(l.Value) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func withLabel(labels []Label, name, value string) []Label {
+	l := make([]Label, 0, len(labels)+1)
+	l = append(l, labels...)
+	return append(l, Label{Name: name, Value: value})
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
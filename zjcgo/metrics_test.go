@@ -0,0 +1,52 @@
+package zjcgo
+
+import (
+	"github.com/zhengjingcheng/zjcgo/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//按路由模板统计，不同id的请求算在同一个route下
+func TestMetricsRouteLabels(t *testing.T) {
+	registry := metrics.NewRegistry()
+	engine := New()
+	g := engine.Group("user")
+	g.Use(MetricsWithConfig(MetricsConfig{Registry: registry, Namespace: "test"}))
+	g.Get("/get/:id", func(ctx *Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	g.Post("/add", func(ctx *Context) {
+		ctx.String(http.StatusBadRequest, "bad")
+	})
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/user/get/1", nil),
+		httptest.NewRequest(http.MethodGet, "/user/get/2", nil),
+		httptest.NewRequest(http.MethodPost, "/user/add", nil),
+		httptest.NewRequest(http.MethodGet, "/user/missing", nil),
+	} {
+		engine.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	var sb strings.Builder
+	if err := registry.WritePrometheus(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		`test_http_requests_total{method="GET",route="/user/get/:id",status="200"} 2`,
+		`test_http_requests_total{method="POST",route="/user/add",status="400"} 1`,
+		`test_http_request_duration_seconds_count{method="GET",route="/user/get/:id"} 2`,
+		`test_http_requests_in_flight{method="GET"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s\n%s", want, out)
+		}
+	}
+	for _, bad := range []string{"/user/get/1", "/user/missing"} {
+		if strings.Contains(out, `"`+bad+`"`) {
+			t.Errorf("raw path %s used as route label\n%s", bad, out)
+		}
+	}
+}
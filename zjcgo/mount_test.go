@@ -0,0 +1,43 @@
+package zjcgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	engine := New()
+	g := engine.Group("static")
+	calls := 0
+	g.Mount("/files", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	}), func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			calls++
+			next(ctx)
+		}
+	})
+
+	//handler看到的路径去掉了组名和prefix
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/files/a/b.txt?v=1", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "/a/b.txt?v=1" {
+		t.Fatalf("mounted path: %d %q", w.Code, w.Body.String())
+	}
+
+	//访问prefix本身重定向到prefix/
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/files?v=1", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/static/files/?v=1" {
+		t.Fatalf("prefix redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/files/", nil))
+	if w.Body.String() != "/?" {
+		t.Fatalf("prefix root: %q", w.Body.String())
+	}
+	if calls != 3 {
+		t.Fatalf("middleware ran %d times, want 3", calls)
+	}
+}
@@ -0,0 +1,221 @@
+package mserror
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+	带错误码的业务错误，错误码先注册再使用
+	var ErrGoodsNotFound = mserror.Register(10001, http.StatusNotFound, "goods not found")
+	return ErrGoodsNotFound.WithDetail("id", id).Wrap(err)
+	errors.Is(err, ErrGoodsNotFound) 按错误码比较
+	tcp rpc的MsRpcResponse.Code用的也是这个错误码，所以业务错误码只能是1-32767，也不能用200
+*/
+
+type Error struct {
+	Code    int            //业务错误码
+	Message string         //给调用方看的信息
+	Status  int            //对应的http状态码
+	Details map[string]any //附加信息
+	cause   error          //原始错误，只打日志不返回给客户端
+	pcs     []uintptr      //Wrap的时候记录的调用栈
+}
+
+//通用错误码和http状态码一致
+var (
+	ErrBadRequest         = Register(http.StatusBadRequest, http.StatusBadRequest, "bad request")
+	ErrUnauthorized       = Register(http.StatusUnauthorized, http.StatusUnauthorized, "unauthorized")
+	ErrForbidden          = Register(http.StatusForbidden, http.StatusForbidden, "forbidden")
+	ErrNotFound           = Register(http.StatusNotFound, http.StatusNotFound, "not found")
+	ErrConflict           = Register(http.StatusConflict, http.StatusConflict, "conflict")
+	ErrTooManyRequests    = Register(http.StatusTooManyRequests, http.StatusTooManyRequests, "too many requests")
+	ErrInternal           = Register(http.StatusInternalServerError, http.StatusInternalServerError, "internal server error")
+	ErrNotImplemented     = Register(http.StatusNotImplemented, http.StatusNotImplemented, "not implemented")
+	ErrServiceUnavailable = Register(http.StatusServiceUnavailable, http.StatusServiceUnavailable, "service unavailable")
+	ErrTimeout            = Register(http.StatusGatewayTimeout, http.StatusGatewayTimeout, "timeout")
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[int]*Error)
+)
+
+//tcp rpc的MsRpcResponse.Code是int16，200表示成功
+func checkCode(code int) {
+	if code <= 0 || code > math.MaxInt16 || code == http.StatusOK {
+		panic(fmt.Sprintf("mserror: invalid code %d, must be in 1-32767 and not 200", code))
+	}
+}
+
+//注册错误码，错误码不合法或者重复注册直接panic
+func Register(code int, status int, message string) *Error {
+	checkCode(code)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[code]; ok {
+		panic(fmt.Sprintf("mserror: code %d registered twice", code))
+	}
+	e := &Error{Code: code, Message: message, Status: status}
+	registry[code] = e
+	return e
+}
+
+//按错误码找注册的错误
+func Lookup(code int) (*Error, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	e, ok := registry[code]
+	return e, ok
+}
+
+//所有注册的错误码，按码排序
+func Codes() []*Error {
+	registryMu.RLock()
+	list := make([]*Error, 0, len(registry))
+	for _, e := range registry {
+		list = append(list, e)
+	}
+	registryMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+//不注册，直接创建一个错误，错误码的要求和Register一样
+func New(code int, status int, message string) *Error {
+	checkCode(code)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return &Error{Code: code, Message: message, Status: status}
+}
+
+//按错误码还原错误，没注册过的按500处理，message为空用注册的信息
+func FromCode(code int, message string) *Error {
+	e, ok := Lookup(code)
+	if !ok {
+		e = &Error{Code: code, Status: http.StatusInternalServerError}
+	}
+	e = e.clone()
+	if message != "" {
+		e.Message = message
+	}
+	return e
+}
+
+//任意错误转成*Error，不是业务错误的包成500
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return ErrInternal.Wrap(err)
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d: %s", e.Code, e.Message)
+	if e.cause != nil {
+		sb.WriteString(": ")
+		sb.WriteString(e.cause.Error())
+	}
+	return sb.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+//错误码相同就认为是同一个错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+//Wrap时的调用栈，日志的JsonFormatter会写到error.stack里，没有Wrap过返回空
+func (e *Error) Stack() string {
+	if len(e.pcs) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	frames := runtime.CallersFrames(e.pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	if e.Details != nil {
+		c.Details = make(map[string]any, len(e.Details))
+		for k, v := range e.Details {
+			c.Details[k] = v
+		}
+	}
+	return &c
+}
+
+//下面的方法都返回副本，注册的错误不会被修改
+
+//包一个原因，同时记录调用的位置
+func (e *Error) Wrap(cause error) *Error {
+	c := e.clone()
+	c.cause = cause
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	c.pcs = pcs[:n]
+	return c
+}
+
+func (e *Error) WithMessage(message string) *Error {
+	c := e.clone()
+	c.Message = message
+	return c
+}
+
+func (e *Error) WithMessagef(format string, args ...any) *Error {
+	return e.WithMessage(fmt.Sprintf(format, args...))
+}
+
+func (e *Error) WithDetail(key string, value any) *Error {
+	c := e.clone()
+	if c.Details == nil {
+		c.Details = make(map[string]any)
+	}
+	c.Details[key] = value
+	return c
+}
+
+func (e *Error) WithDetails(details map[string]any) *Error {
+	c := e.clone()
+	if c.Details == nil {
+		c.Details = make(map[string]any, len(details))
+	}
+	for k, v := range details {
+		c.Details[k] = v
+	}
+	return c
+}
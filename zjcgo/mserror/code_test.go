@@ -0,0 +1,81 @@
+package mserror
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var errGoodsNotFound = Register(10001, http.StatusNotFound, "goods not found")
+
+func TestErrorIsAndWrap(t *testing.T) {
+	cause := errors.New("record not found")
+	err := fmt.Errorf("find: %w", errGoodsNotFound.WithDetail("id", 1).Wrap(cause))
+	if !errors.Is(err, errGoodsNotFound) || !errors.Is(err, cause) {
+		t.Fatalf("errors.Is failed for %v", err)
+	}
+	e := From(err)
+	if e.Status != http.StatusNotFound || e.Details["id"] != 1 {
+		t.Fatalf("From lost fields: %+v", e)
+	}
+	if errGoodsNotFound.Details != nil || errGoodsNotFound.Cause() != nil {
+		t.Fatal("registered error was modified")
+	}
+	if From(cause).Code != http.StatusInternalServerError {
+		t.Fatal("plain error should become internal error")
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for duplicate code")
+		}
+	}()
+	Register(10001, http.StatusBadRequest, "again")
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	err := status.Convert(errGoodsNotFound.WithDetail("id", 7)).Err()
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("grpc code %v", status.Code(err))
+	}
+	e := FromGRPC(err)
+	if e.Code != 10001 || e.Status != http.StatusNotFound || e.Details["id"] != "7" {
+		t.Fatalf("FromGRPC = %+v", e)
+	}
+	if FromGRPC(status.Error(codes.Unavailable, "down")).Status != http.StatusServiceUnavailable {
+		t.Fatal("plain grpc status not mapped")
+	}
+	//服务端的错误码客户端没注册，状态码从grpc的code推出来
+	e = FromGRPC(status.Convert(New(10999, http.StatusConflict, "exists")).Err())
+	if e.Code != 10999 || e.Status != http.StatusConflict || e.Message != "exists" {
+		t.Fatalf("unregistered code FromGRPC = %+v", e)
+	}
+}
+
+func TestInvalidCode(t *testing.T) {
+	for _, code := range []int{0, http.StatusOK, 40000} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("code %d accepted", code)
+				}
+			}()
+			New(code, http.StatusBadRequest, "bad")
+		}()
+	}
+}
+
+func TestWrapRecordsStack(t *testing.T) {
+	if errGoodsNotFound.Stack() != "" {
+		t.Fatal("registered error should not carry a stack")
+	}
+	if s := errGoodsNotFound.Wrap(errors.New("db")).Stack(); !strings.Contains(s, "TestWrapRecordsStack") {
+		t.Fatalf("stack %q", s)
+	}
+}
@@ -29,6 +29,14 @@ func (e *MsError) Result(fuc ErrorFuc) {
 	e.errorFuc = fuc
 }
 
+//有没有通过Result设置处理函数
+func (e *MsError) HasResult() bool {
+	return e.errorFuc != nil
+}
+
 func (e *MsError) ExecResult() {
+	if e.errorFuc == nil {
+		return
+	}
 	e.errorFuc(e)
 }
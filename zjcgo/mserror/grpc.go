@@ -0,0 +1,129 @@
+package mserror
+
+import (
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+)
+
+/*
+	grpc的status.FromError会调用GRPCStatus，所以grpc服务直接返回*Error就行
+	错误码放在ErrorInfo的Reason里，客户端用FromGRPC还原
+*/
+
+const grpcDomain = "zjcgo"
+
+//http状态码对应的grpc状态码
+func GRPCCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 400 && httpStatus < 500 {
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+//grpc状态码对应的http状态码
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(GRPCCode(e.Status), e.Message)
+	info := &errdetails.ErrorInfo{
+		Reason: strconv.Itoa(e.Code),
+		Domain: grpcDomain,
+	}
+	if len(e.Details) > 0 {
+		info.Metadata = make(map[string]string, len(e.Details))
+		for k, v := range e.Details {
+			info.Metadata[k] = fmt.Sprint(v)
+		}
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+//grpc客户端拿到的错误还原成*Error，不是grpc错误的返回nil
+func FromGRPC(err error) *Error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return nil
+	}
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != grpcDomain {
+			continue
+		}
+		code, err := strconv.Atoi(info.Reason)
+		if err != nil {
+			continue
+		}
+		e := FromCode(code, st.Message())
+		//客户端没注册这个错误码，状态码按grpc的code来，不要都变成500
+		if _, ok := Lookup(code); !ok {
+			e.Status = HTTPStatus(st.Code())
+		}
+		for k, v := range info.Metadata {
+			e = e.WithDetail(k, v)
+		}
+		return e
+	}
+	httpStatus := HTTPStatus(st.Code())
+	return New(httpStatus, httpStatus, st.Message())
+}
@@ -0,0 +1,41 @@
+package mserror
+
+import (
+	"net/http"
+	"strconv"
+)
+
+/*
+	RFC 7807 application/problem+json
+	type没有单独的文档地址时用about:blank，title就是状态码的描述
+*/
+
+//设置以后type为 ProblemTypeBase + 错误码，比如 https://example.com/errors/10001
+var ProblemTypeBase = ""
+
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     int            `json:"code"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+//instance一般是请求路径
+func (e *Error) Problem(instance string) *Problem {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Message,
+		Instance: instance,
+		Code:     e.Code,
+		Details:  e.Details,
+	}
+	if ProblemTypeBase != "" {
+		p.Type = ProblemTypeBase + strconv.Itoa(e.Code)
+	}
+	return p
+}
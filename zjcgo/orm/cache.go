@@ -0,0 +1,116 @@
+package orm
+
+import (
+	"fmt"
+	"github.com/zhengjingcheng/zjcgo/cache"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/*
+	查询结果缓存，默认不开启
+	db.SetQueryCache(cache.New[string, any](cache.Config[string, any]{MaxEntries: 1000, TTL: time.Minute}))
+	开启后SelectOne、Select、Count的结果按 表+sql+参数 缓存，
+	同一张表Insert、Update、Delete后这张表的缓存失效，事务里改的表Commit时再失效一次，Exec执行原生sql会清空所有缓存
+*/
+
+//每张表一个版本号，失效时加一。查询前记下版本号，查完版本变了就不写缓存，
+//否则查询期间并发的Update失效以后，旧结果又会被缓存进去
+type cacheGenerations struct {
+	mu     sync.Mutex
+	all    uint64 //清空所有缓存的次数
+	tables map[string]uint64
+}
+
+func (g *cacheGenerations) get(table string) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.all + g.tables[table]
+}
+
+func (db *ZjcDb) SetQueryCache(c *cache.Cache[string, any]) {
+	db.queryCache = c
+	db.cacheGen = &cacheGenerations{tables: make(map[string]uint64)}
+}
+
+func (db *ZjcDb) QueryCache() *cache.Cache[string, any] {
+	return db.queryCache
+}
+
+//这次查询不走缓存
+func (s *ZjcSeeion) NoCache() *ZjcSeeion {
+	s.noCache = true
+	return s
+}
+
+func (s *ZjcSeeion) cacheEnabled() bool {
+	return s.db.queryCache != nil && !s.noCache && !s.beginTx
+}
+
+//表名开头，失效的时候按表名前缀删
+func (s *ZjcSeeion) cacheKey(t reflect.Type, query string) string {
+	return fmt.Sprintf("%s\x00%v\x00%s\x00%#v", s.tableName, t, query, s.whereValues)
+}
+
+//事务里改过的表提交的时候还要再删一次，不然提交之前别的请求又会把旧数据缓存进去
+func (s *ZjcSeeion) invalidateCache() {
+	if s.db.queryCache == nil {
+		return
+	}
+	if s.beginTx {
+		if s.txTables == nil {
+			s.txTables = make(map[string]struct{})
+		}
+		s.txTables[s.tableName] = struct{}{}
+	}
+	s.invalidateTable(s.tableName)
+}
+
+//加版本号和删缓存在同一把锁里，和setCache互斥
+func (s *ZjcSeeion) invalidateTable(table string) {
+	gen := s.db.cacheGen
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+	gen.tables[table]++
+	prefix := table + "\x00"
+	s.db.queryCache.DeleteFunc(func(key string, value any) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+func (db *ZjcDb) clearQueryCache() {
+	if db.queryCache == nil {
+		return
+	}
+	db.cacheGen.mu.Lock()
+	defer db.cacheGen.mu.Unlock()
+	db.cacheGen.all++
+	db.queryCache.Clear()
+}
+
+//查询之前调用
+func (s *ZjcSeeion) cacheGeneration() uint64 {
+	return s.db.cacheGen.get(s.tableName)
+}
+
+//查询期间表的缓存失效过就不写
+func (s *ZjcSeeion) setCache(key string, gen uint64, value any) {
+	g := s.db.cacheGen
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.all+g.tables[s.tableName] != gen {
+		return
+	}
+	s.db.queryCache.Set(key, value)
+}
+
+//事务提交以后让改过的表的缓存失效
+func (s *ZjcSeeion) invalidateTxTables() {
+	if s.db.queryCache != nil {
+		for table := range s.txTables {
+			s.invalidateTable(table)
+		}
+	}
+	s.txTables = nil
+}
@@ -1,21 +1,27 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/zhengjingcheng/zjcgo/cache"
 	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"github.com/zhengjingcheng/zjcgo/trace"
 	"reflect"
 	"strings"
 	"time"
 )
 
 type ZjcDb struct {
-	db     *sql.DB
-	logger *zjcLog.Logger
-	Prefix string
+	db         *sql.DB
+	logger     *zjcLog.Logger
+	Prefix     string
+	queryCache *cache.Cache[string, any] //查询结果缓存
+	cacheGen   *cacheGenerations
 }
 type ZjcSeeion struct {
+	ctx         context.Context //用于链路追踪
 	db          *ZjcDb
 	tx          *sql.Tx //事务
 	beginTx     bool    //是否开启事务
@@ -26,6 +32,8 @@ type ZjcSeeion struct {
 	updateParam strings.Builder
 	whereParam  strings.Builder
 	whereValues []any
+	noCache     bool                //不走查询缓存
+	txTables    map[string]struct{} //事务里改过的表，提交后让缓存失效
 }
 
 func Open(driverName string, source string) *ZjcDb {
@@ -57,13 +65,24 @@ func (db *ZjcDb) Close() error {
 	return db.db.Close()
 }
 
+//连接池的统计信息
+func (db *ZjcDb) Stats() sql.DBStats {
+	return db.db.Stats()
+}
+
+//健康检查用
+func (db *ZjcDb) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
 func (db *ZjcDb) SetMaxIdleConns(n int) {
 	db.db.SetMaxIdleConns(5)
 }
 
 func (db *ZjcDb) New(data any) *ZjcSeeion {
 	m := &ZjcSeeion{
-		db: db,
+		ctx: context.Background(),
+		db:  db,
 	}
 	t := reflect.TypeOf(data)
 	//必须传递指针
@@ -77,6 +96,26 @@ func (db *ZjcDb) New(data any) *ZjcSeeion {
 	}
 	return m
 }
+
+//设置上下文，sql执行会作为ctx中span的子span记录下来
+func (s *ZjcSeeion) WithContext(ctx context.Context) *ZjcSeeion {
+	s.ctx = ctx
+	return s
+}
+
+//开启一个数据库操作的span
+func (s *ZjcSeeion) startSpan(operation string, query string) *trace.Span {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := trace.DefaultTracer().Start(ctx, "orm "+operation, trace.WithKind(trace.KindClient))
+	span.SetAttribute("db.operation", operation)
+	span.SetAttribute("db.table", s.tableName)
+	span.SetAttribute("db.statement", query)
+	return span
+}
+
 func (s *ZjcSeeion) Table(name string) *ZjcSeeion {
 	s.tableName = name
 	return s
@@ -87,6 +126,8 @@ func (s *ZjcSeeion) Insert(data any) (int64, int64, error) {
 	s.fieldNames(data)
 	query := fmt.Sprintf("insert into %s (%s) values (%s)", s.tableName, strings.Join(s.fieldName, ","), strings.Join(s.placeHolder, ","))
 	s.db.logger.Info(query)
+	span := s.startSpan("insert", query)
+	defer span.End()
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
@@ -95,10 +136,12 @@ func (s *ZjcSeeion) Insert(data any) (int64, int64, error) {
 		stmt, err = s.db.db.Prepare(query)
 	}
 	if err != nil {
+		span.RecordError(err)
 		return -1, -1, err
 	}
 	r, err := stmt.Exec(s.values...)
 	if err != nil {
+		span.RecordError(err)
 		return -1, -1, err
 	}
 	id, err := r.LastInsertId()
@@ -109,7 +152,7 @@ func (s *ZjcSeeion) Insert(data any) (int64, int64, error) {
 	if err != nil {
 		return -1, -1, err
 	}
-	s.tx.Commit()
+	s.invalidateCache()
 	return id, affected, nil
 }
 func (s *ZjcSeeion) fieldNames(data any) {
@@ -223,13 +266,17 @@ func (s *ZjcSeeion) Update(data ...any) (int64, int64, error) {
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
 	s.db.logger.Info(sb.String())
+	span := s.startSpan("update", sb.String())
+	defer span.End()
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
+		span.RecordError(err)
 		return -1, -1, err
 	}
 	s.values = append(s.values, s.whereValues...)
 	r, err := stmt.Exec(s.values...)
 	if err != nil {
+		span.RecordError(err)
 		return -1, -1, err
 	}
 	id, err := r.LastInsertId()
@@ -240,6 +287,7 @@ func (s *ZjcSeeion) Update(data ...any) (int64, int64, error) {
 	if err != nil {
 		return -1, -1, err
 	}
+	s.invalidateCache()
 	return id, affected, nil
 }
 
@@ -248,9 +296,21 @@ func (s *ZjcSeeion) Count() (int64, error) {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
+	var key string
+	var gen uint64
+	if s.cacheEnabled() {
+		key = s.cacheKey(nil, sb.String())
+		gen = s.cacheGeneration()
+		if v, ok := s.db.queryCache.Get(key); ok {
+			return v.(int64), nil
+		}
+	}
 	s.db.logger.Info(sb.String())
+	span := s.startSpan("count", sb.String())
+	defer span.End()
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
+		span.RecordError(err)
 		return 0, err
 	}
 	row := stmt.QueryRow(s.whereValues...)
@@ -262,6 +322,9 @@ func (s *ZjcSeeion) Count() (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if key != "" {
+		s.setCache(key, gen, result)
+	}
 	return result, nil
 }
 
@@ -335,14 +398,19 @@ func (s *ZjcSeeion) Delete() (int64, error) {
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
 	s.db.logger.Info(sb.String())
+	span := s.startSpan("delete", sb.String())
+	defer span.End()
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
+		span.RecordError(err)
 		return 0, err
 	}
 	r, err := stmt.Exec(s.whereValues...)
 	if err != nil {
+		span.RecordError(err)
 		return 0, err
 	}
+	s.invalidateCache()
 	return r.RowsAffected()
 }
 
@@ -376,12 +444,16 @@ func (s *ZjcSeeion) InsertBash(data []any) (int64, int64, error) {
 	}
 	s.batchValues(data)
 	s.db.logger.Info(sb.String())
+	span := s.startSpan("insert", sb.String())
+	defer span.End()
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
+		span.RecordError(err)
 		return -1, -1, err
 	}
 	r, err := stmt.Exec(s.values...)
 	if err != nil {
+		span.RecordError(err)
 		return -1, -1, err
 	}
 	id, err := r.LastInsertId()
@@ -392,6 +464,7 @@ func (s *ZjcSeeion) InsertBash(data []any) (int64, int64, error) {
 	if err != nil {
 		return -1, -1, err
 	}
+	s.invalidateCache()
 	return id, affected, nil
 }
 
@@ -436,29 +509,40 @@ func (s *ZjcSeeion) batchValues(data []any) {
 	原生sql的支持
 */
 func (s *ZjcSeeion) Exec(sql string, values ...any) (int64, error) {
+	span := s.startSpan("exec", sql)
+	defer span.End()
 	stmt, err := s.db.db.Prepare(sql)
 	if err != nil {
+		span.RecordError(err)
 		return 0, err
 	}
-	r, err := stmt.Exec(values)
+	r, err := stmt.Exec(values...)
 	if err != nil {
+		span.RecordError(err)
 		return 0, err
 	}
+	//原生sql不知道改了哪张表，缓存全部清掉
+	s.db.clearQueryCache()
 	if strings.Contains(strings.ToLower(sql), "insert") {
 		return r.LastInsertId()
 	}
 	return r.RowsAffected()
 }
 func (s *ZjcSeeion) QueryRow(sql string, data any, queryValues ...any) error {
+	span := s.startSpan("query", sql)
+	defer span.End()
 	t := reflect.TypeOf(data)
 	stmt, err := s.db.db.Prepare(sql)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 	rows, err := stmt.Query(queryValues...)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
+	defer rows.Close()
 	columns, err := rows.Columns()
 	if err != nil {
 		return err
@@ -520,16 +604,31 @@ func (s *ZjcSeeion) SelectOne(data any, fields ...string) error {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
+	var key string
+	var gen uint64
+	if s.cacheEnabled() {
+		key = s.cacheKey(t, sb.String())
+		gen = s.cacheGeneration()
+		if v, ok := s.db.queryCache.Get(key); ok {
+			reflect.ValueOf(data).Elem().Set(reflect.ValueOf(v))
+			return nil
+		}
+	}
 	s.db.logger.Info(sb.String())
+	span := s.startSpan("select", sb.String())
+	defer span.End()
 
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 	rows, err := stmt.Query(s.whereValues...)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
+	defer rows.Close()
 	//id user_name age
 	columns, err := rows.Columns()
 	if err != nil {
@@ -570,6 +669,10 @@ func (s *ZjcSeeion) SelectOne(data any, fields ...string) error {
 				}
 			}
 		}
+		//查到了才缓存，存的是结构体的拷贝
+		if key != "" {
+			s.setCache(key, gen, vVar.Interface())
+		}
 	}
 	return nil
 }
@@ -590,16 +693,38 @@ func (s *ZjcSeeion) Select(data any, fields ...string) ([]any, error) {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
+	var key string
+	var gen uint64
+	if s.cacheEnabled() {
+		key = s.cacheKey(t, sb.String())
+		gen = s.cacheGeneration()
+		if v, ok := s.db.queryCache.Get(key); ok {
+			//每次返回新的指针，调用方改了不影响缓存
+			cached := v.([]any)
+			result := make([]any, len(cached))
+			for i, item := range cached {
+				p := reflect.New(t.Elem())
+				p.Elem().Set(reflect.ValueOf(item))
+				result[i] = p.Interface()
+			}
+			return result, nil
+		}
+	}
 	s.db.logger.Info(sb.String())
+	span := s.startSpan("select", sb.String())
+	defer span.End()
 
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 	rows, err := stmt.Query(s.whereValues...)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
+	defer rows.Close()
 	//id user_name age
 	columns, err := rows.Columns()
 	if err != nil {
@@ -650,6 +775,13 @@ func (s *ZjcSeeion) Select(data any, fields ...string) ([]any, error) {
 			break
 		}
 	}
+	if key != "" {
+		cached := make([]any, len(result))
+		for i, item := range result {
+			cached[i] = reflect.ValueOf(item).Elem().Interface()
+		}
+		s.setCache(key, gen, cached)
+	}
 	return result, nil
 }
 
@@ -713,6 +845,7 @@ func (s *ZjcSeeion) Commit() error {
 		return err
 	}
 	s.beginTx = false
+	s.invalidateTxTables()
 	return nil
 }
 func (s *ZjcSeeion) Rollback() error {
@@ -721,5 +854,6 @@ func (s *ZjcSeeion) Rollback() error {
 		return err
 	}
 	s.beginTx = false
+	s.txTables = nil
 	return nil
 }
\ No newline at end of file
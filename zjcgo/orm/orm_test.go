@@ -2,9 +2,26 @@ package orm
 
 import (
 	"fmt"
+	"github.com/zhengjingcheng/zjcgo/cache"
 	"testing"
 )
 
 func TestName(t *testing.T) {
 	fmt.Println(Name("UserName"))
 }
+
+func TestQueryCacheInvalidate(t *testing.T) {
+	db := &ZjcDb{}
+	db.SetQueryCache(cache.New[string, any](cache.Config[string, any]{}))
+	user := &ZjcSeeion{db: db, tableName: "user"}
+	goods := &ZjcSeeion{db: db, tableName: "goods"}
+	db.queryCache.Set(user.cacheKey(nil, "select count(*) from user"), int64(1))
+	db.queryCache.Set(goods.cacheKey(nil, "select count(*) from goods"), int64(2))
+	user.invalidateCache()
+	if db.queryCache.Len() != 1 {
+		t.Fatalf("len %d, want 1", db.queryCache.Len())
+	}
+	if _, ok := db.queryCache.Get(goods.cacheKey(nil, "select count(*) from goods")); !ok {
+		t.Fatal("other table's cache should be kept")
+	}
+}
@@ -0,0 +1,135 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/zhengjingcheng/zjcgo/cache"
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"io"
+	"sync/atomic"
+	"testing"
+)
+
+//只够跑insert、delete、count和事务的假驱动，每次Exec把count的结果加一
+type fakeDriver struct{}
+type fakeConn struct{}
+type fakeStmt struct{}
+type fakeTx struct{}
+type fakeResult struct{}
+type fakeRows struct {
+	value int64
+	done  bool
+}
+
+var (
+	fakeCount     int64
+	fakeQueryHook func() //查询读到结果以后调用，用来卡住查询
+)
+
+func (fakeDriver) Open(name string) (driver.Conn, error)   { return fakeConn{}, nil }
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+func (fakeStmt) Close() error                              { return nil }
+func (fakeStmt) NumInput() int                             { return -1 }
+func (fakeTx) Commit() error                               { return nil }
+func (fakeTx) Rollback() error                             { return nil }
+
+func (fakeResult) LastInsertId() (int64, error) { return 1, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	atomic.AddInt64(&fakeCount, 1)
+	return fakeResult{}, nil
+}
+
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{value: atomic.LoadInt64(&fakeCount)}
+	if fakeQueryHook != nil {
+		fakeQueryHook()
+	}
+	return rows, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"count"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func init() {
+	sql.Register("ormfake", fakeDriver{})
+}
+
+type txUser struct {
+	Id   int64
+	Name string
+}
+
+func TestQueryCacheInvalidateOnCommit(t *testing.T) {
+	raw, _ := sql.Open("ormfake", "")
+	db := &ZjcDb{db: raw, logger: zjcLog.New()}
+	db.SetQueryCache(cache.New[string, any](cache.Config[string, any]{}))
+	s := &ZjcSeeion{ctx: context.Background(), db: db, tableName: "user"}
+	key := s.cacheKey(nil, "select count(*) from user")
+	if err := s.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Insert(&txUser{Name: "zjc"}); err != nil {
+		t.Fatal(err)
+	}
+	//事务还没提交，别的请求读到旧数据又缓存起来
+	db.queryCache.Set(key, int64(0))
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.queryCache.Get(key); ok {
+		t.Fatal("stale rows cached before commit survived the commit")
+	}
+}
+
+//查询期间别的请求改了表，查出来的旧结果不能缓存
+func TestQueryCacheConcurrentInvalidate(t *testing.T) {
+	raw, _ := sql.Open("ormfake", "")
+	db := &ZjcDb{db: raw, logger: zjcLog.New()}
+	db.SetQueryCache(cache.New[string, any](cache.Config[string, any]{}))
+	reading := make(chan struct{})
+	release := make(chan struct{})
+	fakeQueryHook = func() {
+		close(reading)
+		<-release
+	}
+	defer func() { fakeQueryHook = nil }()
+	before := atomic.LoadInt64(&fakeCount)
+	done := make(chan int64)
+	go func() {
+		n, err := (&ZjcSeeion{ctx: context.Background(), db: db, tableName: "user"}).Count()
+		if err != nil {
+			t.Error(err)
+		}
+		done <- n
+	}()
+	<-reading
+	fakeQueryHook = nil
+	if _, err := (&ZjcSeeion{ctx: context.Background(), db: db, tableName: "user"}).Delete(); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if n := <-done; n != before {
+		t.Fatalf("count %d, want the value read before delete %d", n, before)
+	}
+	n, err := (&ZjcSeeion{ctx: context.Background(), db: db, tableName: "user"}).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != before+1 {
+		t.Fatalf("count %d after delete, stale result was cached", n)
+	}
+}
@@ -4,39 +4,271 @@ import (
 	"errors"
 	"fmt"
 	"github.com/zhengjingcheng/zjcgo/mserror"
+	"github.com/zhengjingcheng/zjcgo/render"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 )
 
-func detailMsg(err any) string {
-	var sb strings.Builder
-	var pcs = make([]uintptr, 32)
-	n := runtime.Callers(3, pcs)
-	sb.WriteString(fmt.Sprintf("%v\n", err))
-	for _, pc := range pcs[:n] {
-		//函数
-		fn := runtime.FuncForPC(pc)
-		file, line := fn.FileLine(pc)
-		sb.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+/*
+	panic恢复中间件，默认返回500纯文本
+	engine.Use(zjcgo.RecoveryWithConfig(zjcgo.RecoveryConfig{
+		Handler:  zjcgo.RecoveryProblem,
+		Reporter: reporter, //zjcgo.NewFileReporter("./log/panic.log")
+		Redact:   []string{"id_card"},
+	}))
+	客户端断开(broken pipe)不算错误，只打warn日志，不上报也不再写响应
+*/
+
+//写panic响应，err是recover()拿到的值
+type RecoveryHandler func(ctx *Context, err any)
+
+type RecoveryConfig struct {
+	Handler  RecoveryHandler //自定义响应，默认500纯文本
+	Reporter Reporter        //把panic发给错误收集服务
+	//日志和上报里要打码的请求头、查询参数、表单字段(名字包含即打码，不区分大小写)，会加在默认的后面
+	Redact []string
+	//堆栈里保留哪些帧，默认去掉runtime、标准库和框架自己的帧
+	StackFilter func(frame StackFrame) bool
+}
+
+//默认打码的字段
+var defaultRedact = []string{"authorization", "cookie", "password", "passwd", "token", "secret", "api-key", "apikey"}
+
+const redacted = "[REDACTED]"
+
+type recovery struct {
+	handler  RecoveryHandler
+	reporter Reporter
+	redact   []string
+	filter   func(frame StackFrame) bool
+}
+
+func RecoveryWithConfig(conf RecoveryConfig) MiddlewareFunc {
+	r := &recovery{
+		handler:  conf.Handler,
+		reporter: conf.Reporter,
+		filter:   conf.StackFilter,
+	}
+	if r.handler == nil {
+		r.handler = RecoveryText
+	}
+	if r.filter == nil {
+		r.filter = appFrame
+	}
+	r.redact = append(r.redact, defaultRedact...)
+	for _, key := range conf.Redact {
+		r.redact = append(r.redact, strings.ToLower(key))
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return r.handle(next)
 	}
-	return sb.String()
 }
+
+var defaultRecovery = RecoveryWithConfig(RecoveryConfig{})
+
 func Recovery(next HandlerFunc) HandlerFunc {
+	return defaultRecovery(next)
+}
+
+func (r *recovery) handle(next HandlerFunc) HandlerFunc {
 	return func(ctx *Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				if e := err.(error); e != nil {
-					var msError *mserror.MsError
-					if errors.As(e, &msError) {
-						msError.ExecResult()
-						return
-					}
+			err := recover()
+			if err == nil {
+				return
+			}
+			//net/http用它来中断请求，继续往上抛
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			if e, ok := err.(error); ok {
+				var msError *mserror.MsError
+				//没设置Result的按普通panic处理
+				if errors.As(e, &msError) && msError.HasResult() {
+					msError.ExecResult()
+					return
+				}
+				//panic出来的业务错误按正常的错误响应处理
+				var bizError *mserror.Error
+				if errors.As(e, &bizError) {
+					ctx.renderError(e)
+					return
 				}
-				ctx.Logger.Error(detailMsg(err))
-				ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
+				if isBrokenPipe(e) {
+					ctx.Logger.Warnf("client disconnected: %s %s: %v", ctx.R.Method, r.redactURL(ctx.R.URL), e)
+					return
+				}
+			}
+			report := r.newReport(ctx, err)
+			ctx.Logger.With("method", report.Method, "path", report.URL).Error(report.String())
+			if r.reporter != nil {
+				if rerr := r.reporter.Report(ctx.R.Context(), report); rerr != nil {
+					ctx.Logger.Errorf("report panic: %v", rerr)
+				}
+			}
+			//响应已经开始写了，再写也没用
+			if ctx.written() {
+				return
 			}
+			r.handler(ctx, err)
 		}()
 		next(ctx)
 	}
 }
+
+//默认响应
+func RecoveryText(ctx *Context, err any) {
+	ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
+}
+
+//{"code":500,"msg":"Internal Server Error"}
+func RecoveryJSON(ctx *Context, err any) {
+	ctx.JSON(http.StatusInternalServerError, map[string]any{
+		"code": http.StatusInternalServerError,
+		"msg":  http.StatusText(http.StatusInternalServerError),
+	})
+}
+
+//application/problem+json，panic的内容不返回给客户端
+func RecoveryProblem(ctx *Context, err any) {
+	problem := map[string]any{
+		"type":   "about:blank",
+		"title":  http.StatusText(http.StatusInternalServerError),
+		"status": http.StatusInternalServerError,
+	}
+	if ctx.R != nil {
+		problem["instance"] = ctx.R.URL.Path
+	}
+	if id := ctx.RequestID(); id != "" {
+		problem["request_id"] = id
+	}
+	ctx.Render(http.StatusInternalServerError, &render.ProblemJSON{Data: problem})
+}
+
+//客户端断开连接时写响应会报broken pipe或connection reset
+func isBrokenPipe(err error) bool {
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var se *os.SyscallError
+		if errors.As(opErr, &se) {
+			msg := strings.ToLower(se.Error())
+			return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+		}
+	}
+	return false
+}
+
+func (r *recovery) newReport(ctx *Context, err any) *PanicReport {
+	report := &PanicReport{
+		Time:  time.Now(),
+		Panic: fmt.Sprint(err),
+		Stack: r.stack(),
+	}
+	if e, ok := err.(error); ok {
+		report.Type = fmt.Sprintf("%T", e)
+	} else {
+		report.Type = fmt.Sprintf("%T", err)
+	}
+	if ctx.R != nil {
+		report.Method = ctx.R.Method
+		report.URL = r.redactURL(ctx.R.URL)
+		report.Route = ctx.FullPath()
+		report.RemoteAddr = ctx.R.RemoteAddr
+		report.RequestID = ctx.RequestID()
+		report.Header = make(map[string]string, len(ctx.R.Header))
+		for key, values := range ctx.R.Header {
+			report.Header[key] = r.redactValue(key, strings.Join(values, ", "))
+		}
+		//body不再读，只记录已经解析过的表单
+		if ctx.R.PostForm != nil {
+			report.Form = make(map[string]string, len(ctx.R.PostForm))
+			for key, values := range ctx.R.PostForm {
+				report.Form[key] = r.redactValue(key, strings.Join(values, ", "))
+			}
+		}
+	}
+	return report
+}
+
+func (r *recovery) shouldRedact(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range r.redact {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *recovery) redactValue(key, value string) string {
+	if r.shouldRedact(key) {
+		return redacted
+	}
+	return value
+}
+
+func (r *recovery) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	query := u.Query()
+	for key := range query {
+		if r.shouldRedact(key) {
+			query[key] = []string{redacted}
+		}
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+//跳过runtime.Callers、stack、recover的defer函数
+func (r *recovery) stack() []StackFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []StackFrame
+	panicked := false
+	for {
+		f, more := frames.Next()
+		frame := StackFrame{Function: f.Function, File: f.File, Line: f.Line}
+		switch {
+		case f.Function == "runtime.gopanic":
+			//panic之前的是recovery自己的帧
+			panicked = true
+			stack = stack[:0]
+		case strings.HasPrefix(f.Function, "runtime."):
+		case panicked && len(stack) == 0:
+			//panic发生的位置一定保留
+			stack = append(stack, frame)
+		case r.filter(frame):
+			stack = append(stack, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+const frameworkPrefix = "github.com/zhengjingcheng/zjcgo."
+
+//应用代码的帧：不是标准库(包路径第一段没有点)，也不是框架根包
+func appFrame(frame StackFrame) bool {
+	fn := frame.Function
+	if strings.HasPrefix(fn, frameworkPrefix) {
+		return false
+	}
+	i := strings.Index(fn, "/")
+	if i < 0 {
+		return strings.HasPrefix(fn, "main.")
+	}
+	return strings.Contains(fn[:i], ".")
+}
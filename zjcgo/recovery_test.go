@@ -0,0 +1,91 @@
+package zjcgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var reports []*PanicReport
+	engine := New()
+	logs := &strings.Builder{}
+	engine.Logger = zjcLog.New()
+	engine.Logger.Outs = []zjcLog.LoggerWriter{{Level: -1, Out: logs}}
+	engine.Logger.Formatter = &zjcLog.TextFormatter{}
+	engine.Use(RecoveryWithConfig(RecoveryConfig{
+		Handler: RecoveryProblem,
+		Reporter: ReporterFunc(func(ctx context.Context, report *PanicReport) error {
+			reports = append(reports, report)
+			return nil
+		}),
+	}))
+	g := engine.Group("api")
+	g.Get("/string", func(ctx *Context) {
+		panic("boom")
+	})
+	g.Get("/put", func(ctx *Context) {
+		//没设置Result
+		mserror.Default().Put(errors.New("put without result"))
+	})
+	g.Get("/pipe", func(ctx *Context) {
+		panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/string?token=abc&q=1", nil)
+	r.Header.Set("Authorization", "Bearer abc")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/problem+json") {
+		t.Fatalf("status %d content-type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var problem map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem["status"] != float64(500) {
+		t.Fatalf("bad problem body %s", w.Body.String())
+	}
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports", len(reports))
+	}
+	report := reports[0]
+	if report.Panic != "boom" || report.Header["Authorization"] != redacted || strings.Contains(report.URL, "abc") {
+		t.Fatalf("report not redacted: %+v", report)
+	}
+	if len(report.Stack) == 0 || !strings.HasSuffix(report.Stack[0].File, "recovery_test.go") {
+		t.Fatalf("panic site missing from stack %v", report.Stack)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/put", nil))
+	if w.Code != http.StatusInternalServerError || len(reports) != 2 {
+		t.Fatalf("MsError without result: status %d, %d reports", w.Code, len(reports))
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/pipe", nil))
+	if len(reports) != 2 || strings.Count(logs.String(), "level=ERROR") != 2 || !strings.Contains(logs.String(), "level=WARN") {
+		t.Fatalf("broken pipe reported as error: %s", logs.String())
+	}
+}
+
+func TestAppFrame(t *testing.T) {
+	for fn, want := range map[string]bool{
+		"main.main": true,
+		"github.com/zjc/goodscenter/service.(*S).Find": true,
+		"fmt.Println":                    false,
+		"net/http.HandlerFunc.ServeHTTP": false,
+		"github.com/zhengjingcheng/zjcgo.(*Engine).ServeHTTP": false,
+	} {
+		if got := appFrame(StackFrame{Function: fn}); got != want {
+			t.Fatalf("appFrame(%s) = %v", fn, got)
+		}
+	}
+}
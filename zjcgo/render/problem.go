@@ -0,0 +1,25 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+//RFC 7807 错误响应，Content-Type是application/problem+json
+type ProblemJSON struct {
+	Data any
+}
+
+func (p *ProblemJSON) Render(w http.ResponseWriter) error {
+	p.WriteContentType(w)
+	jsonData, err := json.Marshal(p.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+
+func (p *ProblemJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/problem+json;charset=utf-8")
+}
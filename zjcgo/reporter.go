@@ -0,0 +1,98 @@
+package zjcgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	panic上报，实现Reporter接口就可以接入sentry之类的错误收集服务
+	本地用文件：
+	reporter, err := zjcgo.NewFileReporter("./log/panic.log")
+	engine.OnShutdown(func() { reporter.Close() })
+*/
+
+type Reporter interface {
+	Report(ctx context.Context, report *PanicReport) error
+}
+
+//panic的现场信息，请求里的敏感字段已经打码
+type PanicReport struct {
+	Time       time.Time         `json:"time"`
+	Panic      string            `json:"panic"`
+	Type       string            `json:"type"`
+	Stack      []StackFrame      `json:"stack"`
+	Method     string            `json:"method,omitempty"`
+	URL        string            `json:"url,omitempty"`
+	Route      string            `json:"route,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Header     map[string]string `json:"header,omitempty"`
+	Form       map[string]string `json:"form,omitempty"`
+}
+
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s\n\t%s:%d", f.Function, f.File, f.Line)
+}
+
+//打日志用的格式
+func (r *PanicReport) String() string {
+	var sb strings.Builder
+	sb.WriteString("panic: ")
+	sb.WriteString(r.Panic)
+	for _, f := range r.Stack {
+		sb.WriteString("\n")
+		sb.WriteString(f.String())
+	}
+	return sb.String()
+}
+
+//包装一个函数当Reporter用
+type ReporterFunc func(ctx context.Context, report *PanicReport) error
+
+func (f ReporterFunc) Report(ctx context.Context, report *PanicReport) error {
+	return f(ctx, report)
+}
+
+//每个panic写一行json
+type FileReporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileReporter(path string) (*FileReporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileReporter{file: file}, nil
+}
+
+func (r *FileReporter) Report(ctx context.Context, report *PanicReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(data)
+	return err
+}
+
+func (r *FileReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,54 @@
+package zjcgo
+
+import (
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"github.com/zhengjingcheng/zjcgo/requestid"
+)
+
+//请求ID在Context中保存的key
+const RequestIDKey = "requestId"
+
+type RequestIDConfig struct {
+	Header    string        //读取和返回的请求头，默认 X-Request-ID
+	Generator func() string //生成请求ID的方法
+}
+
+//请求ID中间件 有则沿用，没有则生成
+func RequestIDWithConfig(conf RequestIDConfig, next HandlerFunc) HandlerFunc {
+	header := conf.Header
+	if header == "" {
+		header = requestid.HeaderKey
+	}
+	generator := conf.Generator
+	if generator == nil {
+		generator = requestid.New
+	}
+	return func(ctx *Context) {
+		id := ctx.R.Header.Get(header)
+		if !requestid.Valid(id) {
+			id = generator()
+		}
+		ctx.Set(RequestIDKey, id)
+		//放到request的context中，调用rpc的时候透传出去
+		c := requestid.NewContext(ctx.R.Context(), id)
+		if ctx.Logger != nil {
+			ctx.Logger = ctx.Logger.With("request_id", id)
+			c = zjcLog.IntoContext(c, ctx.Logger)
+		}
+		ctx.R = ctx.R.WithContext(c)
+		ctx.W.Header().Set(header, id)
+		next(ctx)
+	}
+}
+
+func RequestID(next HandlerFunc) HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{}, next)
+}
+
+//获取当前请求的请求ID
+func (c *Context) RequestID() string {
+	if c.R == nil {
+		return ""
+	}
+	return requestid.FromContext(c.R.Context())
+}
@@ -0,0 +1,58 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+/*
+	请求ID在 http、日志、rpc 之间透传，方便把一次调用链路串起来
+*/
+
+const (
+	HeaderKey   = "X-Request-ID" //http头
+	MetadataKey = "x-request-id" //rpc元数据（grpc要求小写）
+	maxLength   = 128
+)
+
+type ctxKey struct{}
+
+//生成一个新的请求ID
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+//把请求ID放进context
+func NewContext(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+//从context中取出请求ID，没有返回空字符串
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+//校验外部传进来的请求ID，防止过长或者带控制字符的值写进日志
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
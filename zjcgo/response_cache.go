@@ -0,0 +1,185 @@
+package zjcgo
+
+import (
+	"github.com/zhengjingcheng/zjcgo/httpcache"
+	"github.com/zhengjingcheng/zjcgo/internal/singleflight"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+	服务端响应缓存，GET/HEAD请求按 路径+参数+指定的请求头 缓存整个响应
+	cache := zjcgo.NewResponseCache(zjcgo.ResponseCacheConfig{TTL: time.Minute, Tags: ...})
+	group.Get("/find", cache.Handle(handler))
+	数据变了调用 cache.InvalidateTags("goods")
+	要直接包在handler外面，后注册的中间件在外层，不管是路由级别还是group.Use，
+	都会把访问日志、指标、链路追踪、Recovery挡在缓存里面，命中缓存时它们就不执行了
+*/
+
+type ResponseCacheConfig struct {
+	Store       httpcache.Store             //默认分片的内存LRU
+	TTL         time.Duration               //默认1分钟
+	VaryHeaders []string                    //参与缓存key的请求头，比如Accept-Language
+	Statuses    []int                       //可以缓存的状态码，默认只缓存200
+	Tags        func(ctx *Context) []string //响应打上的标签，用来批量失效
+	Skip        func(ctx *Context) bool     //返回true的请求不走缓存
+}
+
+type ResponseCache struct {
+	conf  ResponseCacheConfig
+	store httpcache.Store
+	group singleflight.Group[string, *httpcache.Response]
+}
+
+func NewResponseCache(conf ResponseCacheConfig) *ResponseCache {
+	if conf.Store == nil {
+		conf.Store = httpcache.NewLRUStore(10000, 16)
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = time.Minute
+	}
+	if len(conf.Statuses) == 0 {
+		conf.Statuses = []int{http.StatusOK}
+	}
+	return &ResponseCache{conf: conf, store: conf.Store}
+}
+
+//让带这些标签的缓存失效
+func (rc *ResponseCache) InvalidateTags(tags ...string) {
+	rc.store.DeleteTags(tags...)
+}
+
+func (rc *ResponseCache) Handle(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		if ctx.R.Method != http.MethodGet && ctx.R.Method != http.MethodHead {
+			next(ctx)
+			return
+		}
+		if rc.conf.Skip != nil && rc.conf.Skip(ctx) {
+			next(ctx)
+			return
+		}
+		key := rc.key(ctx)
+		if rsp, ok := rc.store.Get(key); ok {
+			rc.write(ctx, rsp, "HIT")
+			return
+		}
+		//同一个key同时只有一个请求去执行handler，其他请求等着用它的结果
+		var own *httpcache.Response
+		leader := false
+		rsp, err, _ := rc.group.Do(key, func() (*httpcache.Response, error) {
+			leader = true
+			own = rc.capture(ctx, next)
+			if own == nil || !rc.cacheable(own) {
+				return nil, nil
+			}
+			own.Expires = time.Now().Add(rc.conf.TTL)
+			if rc.conf.Tags != nil {
+				own.Tags = rc.conf.Tags(ctx)
+			}
+			rc.store.Set(key, own)
+			return own, nil
+		})
+		if leader {
+			if own != nil {
+				rc.write(ctx, own, "MISS")
+			}
+			return
+		}
+		if err != nil || rsp == nil {
+			//执行的请求结果不能缓存，自己再执行一次
+			next(ctx)
+			return
+		}
+		rc.write(ctx, rsp, "HIT")
+	}
+}
+
+//执行handler并把响应收集起来，handler里flush过的已经直接写给客户端了，返回nil
+func (rc *ResponseCache) capture(ctx *Context, next HandlerFunc) *httpcache.Response {
+	w := ctx.W
+	before := w.Header().Clone()
+	bw := newBufferWriter(w)
+	ctx.W = bw
+	defer func() {
+		ctx.W = w
+	}()
+	next(ctx)
+	if bw.passthrough || !bw.wroteHeader {
+		return nil
+	}
+	//只记录handler自己加的头，前面中间件加的（比如X-Request-ID）每个请求都不一样
+	header := make(http.Header)
+	for k, v := range w.Header() {
+		if !headerEqual(before[k], v) {
+			header[k] = append([]string(nil), v...)
+		}
+	}
+	body := make([]byte, len(bw.Body()))
+	copy(body, bw.Body())
+	return &httpcache.Response{Status: bw.status, Header: header, Body: body}
+}
+
+func (rc *ResponseCache) cacheable(rsp *httpcache.Response) bool {
+	if rsp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	cc := rsp.Header.Get("Cache-Control")
+	if strings.Contains(cc, "no-store") || strings.Contains(cc, "private") {
+		return false
+	}
+	for _, status := range rc.conf.Statuses {
+		if rsp.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (rc *ResponseCache) write(ctx *Context, rsp *httpcache.Response, state string) {
+	//复制一份，后面对响应头的修改不能影响缓存
+	header := ctx.W.Header()
+	for k, v := range rsp.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("X-Cache", state)
+	if rsp.Status == http.StatusOK && ctx.IsFresh() {
+		ctx.NotModified()
+		return
+	}
+	ctx.W.WriteHeader(rsp.Status)
+	ctx.StatusCode = rsp.Status
+	_, _ = ctx.W.Write(rsp.Body)
+}
+
+//method path?排好序的参数|请求头
+func (rc *ResponseCache) key(ctx *Context) string {
+	var b strings.Builder
+	b.WriteString(ctx.R.Method)
+	b.WriteByte(' ')
+	b.WriteString(ctx.R.URL.Path)
+	if query := ctx.R.URL.Query(); len(query) > 0 {
+		b.WriteByte('?')
+		b.WriteString(query.Encode())
+	}
+	for _, h := range rc.conf.VaryHeaders {
+		b.WriteByte('|')
+		b.WriteString(h)
+		b.WriteByte('=')
+		b.WriteString(ctx.R.Header.Get(h))
+	}
+	return b.String()
+}
+
+func headerEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
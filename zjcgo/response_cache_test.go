@@ -0,0 +1,48 @@
+package zjcgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls, seen := 0, 0
+	engine := New()
+	//包在缓存外面的中间件，命中缓存也要执行
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			seen++
+			next(ctx)
+			//改响应头不能改到缓存里的
+			if v := ctx.W.Header()["X-Tag"]; len(v) > 0 {
+				v[0] = "mutated"
+			}
+		}
+	})
+	g := engine.Group("api")
+	cache := NewResponseCache(ResponseCacheConfig{})
+	g.Get("/goods", cache.Handle(func(ctx *Context) {
+		calls++
+		ctx.W.Header().Set("X-Tag", "v1")
+		ctx.String(http.StatusOK, "goods")
+	}))
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/goods", nil))
+		if w.Body.String() != "goods" || w.Header().Get("X-Tag") != "mutated" {
+			t.Fatalf("request %d: %q %v", i, w.Body.String(), w.Header())
+		}
+		if i > 0 && w.Header().Get("X-Cache") != "HIT" {
+			t.Fatalf("request %d not served from cache", i)
+		}
+	}
+	if calls != 1 || seen != 3 {
+		t.Fatalf("handler calls %d, middleware calls %d", calls, seen)
+	}
+	rsp, ok := cache.store.Get("GET /api/goods")
+	if !ok || rsp.Header.Get("X-Tag") != "v1" {
+		t.Fatalf("cached header corrupted: %v", rsp)
+	}
+}
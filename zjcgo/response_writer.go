@@ -0,0 +1,175 @@
+package zjcgo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+/*
+	对http.ResponseWriter做一层包装，记录状态码和写出的字节数，给日志、指标等中间件使用
+*/
+
+const noWritten = -1
+
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	//返回的状态码
+	Status() int
+	//已经写出的body字节数
+	Size() int
+	//是否已经写过响应头
+	Written() bool
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	size   int
+	status int
+}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.status = http.StatusOK
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	//已经写过头了，重复写没有意义
+	if code > 0 && !w.Written() {
+		w.status = code
+		w.size = 0
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.Written() {
+		w.WriteHeader(w.status)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	if w.size == noWritten {
+		return 0
+	}
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.Written() {
+			w.WriteHeader(w.status)
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return h.Hijack()
+}
+
+//先把body缓存起来，由中间件决定最后怎么写出去(生成ETag、304、缓存响应)
+//调用Flush或者Hijack以后就不再缓存，直接写到底层
+type bufferWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         bytes.Buffer
+}
+
+func newBufferWriter(w http.ResponseWriter) *bufferWriter {
+	return &bufferWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *bufferWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *bufferWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferWriter) Size() int {
+	return w.buf.Len()
+}
+
+func (w *bufferWriter) Written() bool {
+	return w.wroteHeader
+}
+
+//缓存的内容
+func (w *bufferWriter) Body() []byte {
+	return w.buf.Bytes()
+}
+
+//流式输出，把缓存的内容写出去以后改成直接写
+func (w *bufferWriter) Flush() {
+	w.stopBuffering()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bufferWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	w.passthrough = true
+	return h.Hijack()
+}
+
+func (w *bufferWriter) stopBuffering() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
@@ -0,0 +1,26 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"google.golang.org/grpc"
+)
+
+//业务错误被包了一层时grpc认不出来，统一转成带错误码的status
+func grpcError(err error) error {
+	var bizError *mserror.Error
+	if err != nil && errors.As(err, &bizError) {
+		return bizError.GRPCStatus().Err()
+	}
+	return err
+}
+
+func errorUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	return resp, grpcError(err)
+}
+
+func errorStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return grpcError(handler(srv, ss))
+}
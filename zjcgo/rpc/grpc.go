@@ -4,6 +4,8 @@ import (
 	"context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"net"
 	"time"
@@ -31,7 +33,12 @@ func NewGrpcServer(addr string, ops ...ZjcrpcOption) (*ZjcrpcServer, error) {
 	for _, v := range ops {
 		v.Apply(zjc)
 	}
-	server := grpc.NewServer(zjc.ops...)
+	//默认带上元数据透传、链路追踪、指标统计和业务错误转换的拦截器，用户的拦截器链在后面
+	serverOps := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(metadataUnaryServerInterceptor, traceUnaryServerInterceptor, metricsUnaryServerInterceptor, errorUnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(metadataStreamServerInterceptor, traceStreamServerInterceptor, metricsStreamServerInterceptor, errorStreamServerInterceptor),
+	}, zjc.ops...)
+	server := grpc.NewServer(serverOps...)
 	zjc.g = server
 	return zjc, nil
 }
@@ -49,6 +56,15 @@ func (s *ZjcrpcServer) Register(f func(g *grpc.Server)) {
 	s.register = append(s.register, f)
 }
 
+//注册标准的grpc健康检查服务，返回值可以用来修改服务状态
+func (s *ZjcrpcServer) RegisterHealth() *health.Server {
+	hs := health.NewServer()
+	s.Register(func(g *grpc.Server) {
+		grpc_health_v1.RegisterHealthServer(g, hs)
+	})
+	return hs
+}
+
 type ZjcrpcOption interface {
 	Apply(s *ZjcrpcServer)
 }
@@ -73,7 +89,7 @@ type ZjcrpcClient struct {
 
 func NewGrpcClient(config *ZjcrpcClientConfig) (*ZjcrpcClient, error) {
 	var ctx = context.Background()
-	var dialOptions = config.dialOptions
+	var dialOptions = append([]grpc.DialOption{}, config.dialOptions...)
 
 	if config.Block {
 		//阻塞
@@ -84,6 +100,10 @@ func NewGrpcClient(config *ZjcrpcClientConfig) (*ZjcrpcClient, error) {
 		}
 		dialOptions = append(dialOptions, grpc.WithBlock())
 	}
+	dialOptions = append(dialOptions,
+		grpc.WithChainUnaryInterceptor(traceUnaryClientInterceptor, metadataUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(traceStreamClientInterceptor, metadataStreamClientInterceptor),
+	)
 	if config.KeepAlive != nil {
 		dialOptions = append(dialOptions, grpc.WithKeepaliveParams(*config.KeepAlive))
 	}
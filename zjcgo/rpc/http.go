@@ -3,9 +3,11 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/zhengjingcheng/zjcgo/trace"
 	"io"
 	"log"
 	"net/http"
@@ -36,12 +38,17 @@ func NewHttpClient() *zjcHttpClient {
 }
 
 func (c *zjcHttpClient) Get(url string, args map[string]any) ([]byte, error) {
+	return c.GetContext(context.Background(), url, args)
+}
+
+//带context的get请求，context中的请求ID会透传到下游
+func (c *zjcHttpClient) GetContext(ctx context.Context, url string, args map[string]any) ([]byte, error) {
 	//get请求的参数url?
 	if args != nil && len(args) > 0 {
 		url = url + "?" + c.toValues(args)
 	}
 	log.Println(url)
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -68,15 +75,23 @@ func (c *zjcHttpClient) FormRequest(method string, url string, args map[string]a
 }
 
 func (c *zjcHttpClient) PostForm(url string, args map[string]any) ([]byte, error) {
-	request, err := http.NewRequest("POST", url, strings.NewReader(c.toValues(args)))
+	return c.PostFormContext(context.Background(), url, args)
+}
+
+func (c *zjcHttpClient) PostFormContext(ctx context.Context, url string, args map[string]any) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(c.toValues(args)))
 	if err != nil {
 		return nil, err
 	}
 	return c.responseHandle(request)
 }
 func (c *zjcHttpClient) PostJson(url string, args map[string]any) ([]byte, error) {
+	return c.PostJsonContext(context.Background(), url, args)
+}
+
+func (c *zjcHttpClient) PostJsonContext(ctx context.Context, url string, args map[string]any) ([]byte, error) {
 	marshal, _ := json.Marshal(args)
-	request, err := http.NewRequest("POST", url, bytes.NewReader(marshal))
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(marshal))
 	if err != nil {
 		return nil, err
 	}
@@ -94,10 +109,18 @@ func (c *zjcHttpClient) Response(req *http.Request) ([]byte, error) {
 	return c.responseHandle(req)
 }
 func (c *zjcHttpClient) responseHandle(request *http.Request) ([]byte, error) {
+	ctx, span := trace.DefaultTracer().Start(request.Context(), "HTTP "+request.Method, trace.WithKind(trace.KindClient))
+	defer span.End()
+	span.SetAttribute("http.method", request.Method)
+	span.SetAttribute("http.url", request.URL.String())
+	request = request.WithContext(ctx)
+	injectHeader(request)
 	response, err := c.client.Do(request)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
+	span.SetAttribute("http.status_code", response.StatusCode)
 	if response.StatusCode != http.StatusOK {
 		info := fmt.Sprintf("response status is %d", response.StatusCode)
 		return nil, errors.New(info)
@@ -199,19 +222,28 @@ func (c *zjcHttpClient) Do(service string, method string) ZjcService {
 	path := split[1]
 	httpConfig := zjcService.Env()
 
-	f := func(args map[string]any) ([]byte, error) {
+	f := func(ctx context.Context, args map[string]any) ([]byte, error) {
 		if methodType == GET {
-			return c.Get(httpConfig.Prefix()+path, args)
+			return c.GetContext(ctx, httpConfig.Prefix()+path, args)
 		}
 		if methodType == POSTForm {
-			return c.PostForm(httpConfig.Prefix()+path, args)
+			return c.PostFormContext(ctx, httpConfig.Prefix()+path, args)
 		}
 		if methodType == POSTJson {
-			return c.PostJson(httpConfig.Prefix()+path, args)
+			return c.PostJsonContext(ctx, httpConfig.Prefix()+path, args)
 		}
 		return nil, errors.New("no match method type")
 	}
-	fValue := reflect.ValueOf(f)
+	//字段可以声明成 func(args) 或者 func(ctx, args)，带ctx的会透传请求ID
+	var fValue reflect.Value
+	switch vVar.Field(fieldIndex).Interface().(type) {
+	case func(ctx context.Context, args map[string]any) ([]byte, error):
+		fValue = reflect.ValueOf(f)
+	default:
+		fValue = reflect.ValueOf(func(args map[string]any) ([]byte, error) {
+			return f(context.Background(), args)
+		})
+	}
 	vVar.Field(fieldIndex).Set(fValue)
 	return zjcService
 }
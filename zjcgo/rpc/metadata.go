@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"context"
+	"github.com/zhengjingcheng/zjcgo/requestid"
+	"github.com/zhengjingcheng/zjcgo/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+)
+
+/*
+	调用链路上需要透传的元数据（请求ID、traceparent等），http放在header，tcp放在帧里，grpc放在metadata
+*/
+
+//从context中提取需要透传出去的元数据
+func outgoingMetadata(ctx context.Context) map[string]string {
+	md := make(map[string]string)
+	if id := requestid.FromContext(ctx); id != "" {
+		md[requestid.MetadataKey] = id
+	}
+	trace.Inject(ctx, trace.MapCarrier(md))
+	return md
+}
+
+//服务端把收到的元数据恢复到context中
+func incomingContext(ctx context.Context, md map[string]string) context.Context {
+	if id := md[requestid.MetadataKey]; requestid.Valid(id) {
+		ctx = requestid.NewContext(ctx, id)
+	}
+	return trace.Extract(ctx, trace.MapCarrier(md))
+}
+
+//http请求注入元数据
+func injectHeader(req *http.Request) {
+	if id := requestid.FromContext(req.Context()); id != "" && req.Header.Get(requestid.HeaderKey) == "" {
+		req.Header.Set(requestid.HeaderKey, id)
+	}
+	trace.Inject(req.Context(), trace.HeaderCarrier(req.Header))
+}
+
+//grpc客户端拦截器
+func metadataUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(appendOutgoing(ctx), method, req, reply, cc, opts...)
+}
+
+func metadataStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(appendOutgoing(ctx), desc, cc, method, opts...)
+}
+
+func appendOutgoing(ctx context.Context) context.Context {
+	for k, v := range outgoingMetadata(ctx) {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	return ctx
+}
+
+//grpc服务端拦截器
+func metadataUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(restoreIncoming(ctx), req)
+}
+
+func metadataStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextServerStream{ServerStream: ss, ctx: restoreIncoming(ss.Context())})
+}
+
+func restoreIncoming(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	values := make(map[string]string)
+	for k, v := range md {
+		if len(v) > 0 {
+			values[k] = v[0]
+		}
+	}
+	return incomingContext(ctx, values)
+}
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
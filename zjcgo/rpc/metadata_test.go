@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"context"
+	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//上游带请求ID进来，http客户端调用下游时透传出去
+func TestHttpRequestIDRoundTrip(t *testing.T) {
+	var got string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(requestid.HeaderKey)
+	}))
+	defer downstream.Close()
+
+	engine := zjcgo.New()
+	g := engine.Group("order")
+	g.Use(zjcgo.RequestID)
+	g.Get("/find", func(ctx *zjcgo.Context) {
+		if _, err := NewHttpClient().GetContext(ctx.R.Context(), downstream.URL, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/order/find", nil)
+	r.Header.Set(requestid.HeaderKey, "req-1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if got != "req-1" || w.Header().Get(requestid.HeaderKey) != "req-1" {
+		t.Fatalf("downstream got %q, response header %q", got, w.Header().Get(requestid.HeaderKey))
+	}
+
+	//没带的话生成一个，下游和响应头里是同一个
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order/find", nil))
+	if !requestid.Valid(got) || got != w.Header().Get(requestid.HeaderKey) {
+		t.Fatalf("downstream got %q, response header %q", got, w.Header().Get(requestid.HeaderKey))
+	}
+}
+
+type requestIDService struct{}
+
+func (*requestIDService) Echo(ctx context.Context) (string, error) {
+	return requestid.FromContext(ctx), nil
+}
+
+func TestTcpRequestIDRoundTrip(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	server, _ := NewTcpServer("")
+	server.Host, server.Port = "127.0.0.1", port
+	server.Register("requestId", &requestIDService{})
+	go server.Run()
+	defer server.Close()
+
+	option := DefaultOption
+	option.Port = port
+	option.ConnectionTimeout = time.Second
+	client := NewTcpClient(option)
+	for i := 0; i < 50; i++ {
+		if err = client.Connect(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	result, err := client.Invoke(ctx, "requestId", "Echo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp := result.(*MsRpcResponse); rsp.Err() != nil || rsp.Data != "req-1" {
+		t.Fatalf("response %+v", rsp)
+	}
+}
+
+type requestIDHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	got chan string
+}
+
+func (s *requestIDHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.got <- requestid.FromContext(ctx)
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func TestGrpcRequestIDRoundTrip(t *testing.T) {
+	server, err := NewGrpcServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := &requestIDHealthServer{got: make(chan string, 1)}
+	server.Register(func(g *grpc.Server) {
+		grpc_health_v1.RegisterHealthServer(g, hs)
+	})
+	go server.Run()
+	defer server.Stop()
+
+	config := DefaultGrpcClientConfig()
+	config.Address = server.liseten.Addr().String()
+	client, err := NewGrpcClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Conn.Close()
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	if _, err := grpc_health_v1.NewHealthClient(client.Conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-hs.got; got != "req-1" {
+		t.Fatalf("server got request id %q", got)
+	}
+}
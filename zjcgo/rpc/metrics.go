@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"context"
+	"github.com/zhengjingcheng/zjcgo/metrics"
+	"google.golang.org/grpc"
+	"strings"
+	"time"
+)
+
+//rpc服务端指标，按协议、服务、方法统计调用次数、错误次数和耗时
+var (
+	serverCalls = metrics.NewCounterVec("zjcgo_rpc_server_calls_total",
+		"Total number of RPC calls handled by the server.", "protocol", "service", "method")
+	serverErrors = metrics.NewCounterVec("zjcgo_rpc_server_errors_total",
+		"Total number of RPC calls that returned an error.", "protocol", "service", "method")
+	serverDuration = metrics.NewHistogramVec("zjcgo_rpc_server_duration_seconds",
+		"RPC call latency in seconds.", metrics.DefBuckets, "protocol", "service", "method")
+)
+
+func init() {
+	metrics.DefaultRegistry.Register(serverCalls)
+	metrics.DefaultRegistry.Register(serverErrors)
+	metrics.DefaultRegistry.Register(serverDuration)
+}
+
+func observeServer(protocol, service, method string, start time.Time, failed bool) {
+	serverCalls.WithLabelValues(protocol, service, method).Inc()
+	if failed {
+		serverErrors.WithLabelValues(protocol, service, method).Inc()
+	}
+	serverDuration.WithLabelValues(protocol, service, method).Observe(time.Since(start).Seconds())
+}
+
+//grpc的方法名格式为 /package.Service/Method
+func splitFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func metricsUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	service, method := splitFullMethod(info.FullMethod)
+	observeServer("grpc", service, method, start, err != nil)
+	return resp, err
+}
+
+func metricsStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	service, method := splitFullMethod(info.FullMethod)
+	observeServer("grpc", service, method, start, err != nil)
+	return err
+}
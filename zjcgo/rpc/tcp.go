@@ -8,10 +8,14 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"github.com/zhengjingcheng/zjcgo/trace"
 	"io"
 	"log"
+	"math"
 	"net"
 	"reflect"
+	"strconv"
 	"sync/atomic"
 	"time"
 )
@@ -88,6 +92,7 @@ type MsRpcRequest struct {
 	ServiceName string
 	MethodName  string
 	Args        []any
+	Metadata    map[string]string //透传的元数据，比如请求ID
 }
 
 type MsRpcResponse struct {
@@ -99,6 +104,11 @@ type MsRpcResponse struct {
 	Data          any
 }
 
+//调用失败时返回*mserror.Error，业务错误的错误码和服务端一致
+func (r *MsRpcResponse) Err() error {
+	return rspError(r)
+}
+
 type MsRpcServer interface {
 	Register(name string, service interface{})
 	Run()
@@ -198,6 +208,10 @@ func (s *MsTcpServer) Run() {
 	s.listener = listen
 	for {
 		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			//Close以后退出
+			return
+		}
 		if err != nil {
 			log.Println(err)
 			continue
@@ -226,22 +240,55 @@ func (s *MsTcpServer) readHandle(msConn *MsTcpConn) {
 		//查找注册的服务匹配后进行调用，调用完发送到一个channel当中
 		service, ok := s.serviceMap[req.ServiceName]
 		rsp := &MsRpcResponse{RequestId: req.RequestId, CompressType: msg.Header.CompressType, SerializeType: msg.Header.SerializeType}
+		//统计调用指标，未注册的服务和方法统一记成unknown，避免label无限增长
+		start := time.Now()
+		serviceName, methodName := "unknown", "unknown"
+		failed := true
+		//恢复客户端透传的元数据，开启server span
+		ctx, span := trace.DefaultTracer().Start(incomingContext(context.Background(), req.Metadata),
+			"tcp "+req.ServiceName+"/"+req.MethodName, trace.WithKind(trace.KindServer))
+		span.SetAttribute("rpc.system", "zjcrpc")
+		defer func() {
+			observeServer("tcp", serviceName, methodName, start, failed)
+			if failed {
+				msg := rsp.Msg
+				if msg == "" {
+					msg = "rpc call panic"
+				}
+				span.RecordError(errors.New(msg))
+			}
+			span.End()
+		}()
 		if !ok {
 			rsp.Code = 500
 			rsp.Msg = "no service found"
 			msConn.rspChan <- rsp
 			return
 		}
+		serviceName = req.ServiceName
 		v := reflect.ValueOf(service)
 		reflectMethod := v.MethodByName(req.MethodName)
-		args := make([]reflect.Value, len(req.Args))
+		if !reflectMethod.IsValid() {
+			rsp.Code = 500
+			rsp.Msg = "no method found"
+			msConn.rspChan <- rsp
+			return
+		}
+		methodName = req.MethodName
+		args := make([]reflect.Value, 0, len(req.Args)+1)
+		//方法第一个参数是context的话，把客户端透传的元数据恢复进去
+		methodType := reflectMethod.Type()
+		if methodType.NumIn() > 0 && methodType.In(0) == contextType {
+			args = append(args, reflect.ValueOf(ctx))
+		}
 		for i := range req.Args {
-			args[i] = reflect.ValueOf(req.Args[i])
+			args = append(args, reflect.ValueOf(req.Args[i]))
 		}
 		result := reflectMethod.Call(args)
 		if len(result) == 0 {
 			//无返回结果
 			rsp.Code = 200
+			failed = false
 			msConn.rspChan <- rsp
 			return
 		}
@@ -257,15 +304,27 @@ func (s *MsTcpServer) readHandle(msConn *MsTcpConn) {
 		if err != nil {
 			rsp.Code = 500
 			rsp.Msg = err.Error()
+			//业务错误带上自己的错误码，只返回错误信息，不返回包着的原因
+			var bizError *mserror.Error
+			if errors.As(err, &bizError) {
+				rsp.Msg = bizError.Message
+				if bizError.Code > 0 && bizError.Code <= math.MaxInt16 && bizError.Code != 200 {
+					rsp.Code = int16(bizError.Code)
+				}
+			}
+		} else {
+			rsp.Code = 200
+			rsp.Data = resArgs[0]
+			failed = false
 		}
-		rsp.Code = 200
-		rsp.Data = resArgs[0]
 		msConn.rspChan <- rsp
 		log.Println("接收数据成功")
 		return
 	}
 }
 
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
 func (s *MsTcpServer) Close() {
 	if s.listener != nil {
 		s.listener.Close()
@@ -445,7 +504,7 @@ func NewTcpClient(option TcpClientOption) *MsTcpClient {
 }
 
 func (c *MsTcpClient) Connect() error {
-	addr := fmt.Sprintf("%s:%d", c.option.Host, c.option.Port)
+	addr := net.JoinHostPort(c.option.Host, strconv.Itoa(c.option.Port))
 	conn, err := net.DialTimeout("tcp", addr, c.option.ConnectionTimeout)
 	if err != nil {
 		return err
@@ -456,16 +515,24 @@ func (c *MsTcpClient) Connect() error {
 
 var reqId int64
 
-func (c *MsTcpClient) Invoke(ctx context.Context, serviceName string, methodName string, args []any) (any, error) {
+func (c *MsTcpClient) Invoke(ctx context.Context, serviceName string, methodName string, args []any) (result any, err error) {
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithTimeout(ctx, c.option.ConnectionTimeout)
 	defer cancel()
+	//client span，随元数据一起发给服务端
+	ctx, span := trace.DefaultTracer().Start(ctx, "tcp "+serviceName+"/"+methodName, trace.WithKind(trace.KindClient))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("rpc.system", "zjcrpc")
 
 	req := &MsRpcRequest{}
 	req.RequestId = atomic.AddInt64(&reqId, 1)
 	req.ServiceName = serviceName
 	req.MethodName = methodName
-	//req.args = args
+	req.Args = args
+	req.Metadata = outgoingMetadata(ctx)
 
 	headers := make([]byte, 17)
 	//magic number
@@ -507,6 +574,7 @@ func (c *MsTcpClient) Invoke(ctx context.Context, serviceName string, methodName
 	rspChan := make(chan *MsRpcResponse)
 	go c.readHandle(rspChan)
 	rsp := <-rspChan
+	span.RecordError(rspError(rsp))
 	return rsp, nil
 }
 
@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var errGoodsNotFound = mserror.Register(10404, http.StatusNotFound, "goods not found")
+
+type goodsService struct{}
+
+func (*goodsService) Find(ctx context.Context, id int64) (string, error) {
+	if id == 0 {
+		return "", errGoodsNotFound.Wrap(errors.New("select * from goods: secret dsn"))
+	}
+	return "goods", nil
+}
+
+func TestTcpBusinessError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	server, _ := NewTcpServer("")
+	server.Host, server.Port = "127.0.0.1", port
+	server.Register("goods", &goodsService{})
+	go server.Run()
+	defer server.Close()
+
+	call := func(id int64) *MsRpcResponse {
+		option := DefaultOption
+		option.Port = port
+		option.ConnectionTimeout = time.Second
+		client := NewTcpClient(option)
+		var err error
+		for i := 0; i < 50; i++ {
+			if err = client.Connect(); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		result, err := client.Invoke(context.Background(), "goods", "Find", []any{id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.(*MsRpcResponse)
+	}
+
+	rsp := call(0)
+	if rsp.Code != 10404 || rsp.Msg != "goods not found" {
+		t.Fatalf("code %d msg %q", rsp.Code, rsp.Msg)
+	}
+	if err := rsp.Err(); !errors.Is(err, errGoodsNotFound) || mserror.From(err).Status != http.StatusNotFound {
+		t.Fatalf("client error %v", err)
+	}
+	if rsp := call(1); rsp.Err() != nil || rsp.Data != "goods" {
+		t.Fatalf("success call %+v", rsp)
+	}
+}
@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/mserror"
+	"github.com/zhengjingcheng/zjcgo/trace"
+	"google.golang.org/grpc"
+)
+
+//grpc客户端开启client span，后面的元数据拦截器会把它注入到metadata
+func traceUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, span := trace.DefaultTracer().Start(ctx, "grpc "+method, trace.WithKind(trace.KindClient))
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	span.RecordError(err)
+	return err
+}
+
+func traceStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	//流式调用只记录建立流的过程
+	ctx, span := trace.DefaultTracer().Start(ctx, "grpc "+method, trace.WithKind(trace.KindClient))
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	span.RecordError(err)
+	return stream, err
+}
+
+//grpc服务端开启server span，上游的span信息已经由元数据拦截器恢复到ctx中
+func traceUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, span := trace.DefaultTracer().Start(ctx, "grpc "+info.FullMethod, trace.WithKind(trace.KindServer))
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	resp, err := handler(ctx, req)
+	span.RecordError(err)
+	return resp, err
+}
+
+func traceStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := trace.DefaultTracer().Start(ss.Context(), "grpc "+info.FullMethod, trace.WithKind(trace.KindServer))
+	defer span.End()
+	span.SetAttribute("rpc.system", "grpc")
+	err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	span.RecordError(err)
+	return err
+}
+
+//tcp调用返回的错误信息
+func rspError(rsp *MsRpcResponse) error {
+	if rsp == nil {
+		return errors.New("no response")
+	}
+	if rsp.Code != 200 {
+		return mserror.FromCode(int(rsp.Code), rsp.Msg)
+	}
+	return nil
+}
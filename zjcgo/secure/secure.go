@@ -0,0 +1,180 @@
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/config"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+)
+
+/*
+	安全相关的响应头
+	engine.SetFuncMap(secure.FuncMap())
+	engine.LoadTemplate("tpl/*.html")
+	engine.Use(secure.New(secure.FromConfig()))
+	CSP里写$NONCE会替换成每个请求随机生成的nonce，模板里用 <script nonce="{{cspNonce}}">
+	模板数据是nil或map时也可以用{{.cspNonce}}
+*/
+
+const (
+	NoncePlaceholder = "$NONCE"
+	TemplateFuncName = "cspNonce"
+	TemplateKey      = "cspNonce" //Context.Template合并进模板数据的key
+	contextKey       = "zjcgo/secure/nonce"
+)
+
+type Config struct {
+	AllowedHosts          []string //为空不检查Host
+	SSLRedirect           bool     //http请求重定向到https
+	SSLHost               string   //重定向的host，为空用请求的host
+	STSSeconds            int64    //HSTS的max-age，0不设置，只在TLS连接上发送
+	STSIncludeSubdomains  bool
+	STSPreload            bool
+	ContentSecurityPolicy string
+	FrameOptions          string //DENY或者SAMEORIGIN
+	ContentTypeNosniff    bool
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+	//Host不在AllowedHosts里时的处理，默认400
+	BadHostHandler func(ctx *zjcgo.Context)
+}
+
+func DefaultConfig() Config {
+	return Config{
+		STSSeconds:         31536000,
+		FrameOptions:       "DENY",
+		ContentTypeNosniff: true,
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+	}
+}
+
+//默认配置加上app.toml里[secure]的配置
+//[secure]
+//allowed_hosts=["example.com"]
+//ssl_redirect=true
+//content_security_policy="default-src 'self'; script-src 'self' $NONCE"
+func FromConfig() Config {
+	c := DefaultConfig()
+	s := config.Section("secure")
+	c.AllowedHosts = config.StringSlice(s, "allowed_hosts", c.AllowedHosts)
+	c.SSLRedirect = config.Bool(s, "ssl_redirect", c.SSLRedirect)
+	c.SSLHost = config.String(s, "ssl_host", c.SSLHost)
+	c.STSSeconds = config.Int(s, "sts_seconds", c.STSSeconds)
+	c.STSIncludeSubdomains = config.Bool(s, "sts_include_subdomains", c.STSIncludeSubdomains)
+	c.STSPreload = config.Bool(s, "sts_preload", c.STSPreload)
+	c.ContentSecurityPolicy = config.String(s, "content_security_policy", c.ContentSecurityPolicy)
+	c.FrameOptions = config.String(s, "frame_options", c.FrameOptions)
+	c.ContentTypeNosniff = config.Bool(s, "content_type_nosniff", c.ContentTypeNosniff)
+	c.ReferrerPolicy = config.String(s, "referrer_policy", c.ReferrerPolicy)
+	c.PermissionsPolicy = config.String(s, "permissions_policy", c.PermissionsPolicy)
+	return c
+}
+
+func New(conf Config) zjcgo.MiddlewareFunc {
+	if conf.BadHostHandler == nil {
+		conf.BadHostHandler = func(ctx *zjcgo.Context) {
+			ctx.String(http.StatusBadRequest, "bad host")
+		}
+	}
+	sts := ""
+	if conf.STSSeconds > 0 {
+		sts = fmt.Sprintf("max-age=%d", conf.STSSeconds)
+		if conf.STSIncludeSubdomains {
+			sts += "; includeSubDomains"
+		}
+		if conf.STSPreload {
+			sts += "; preload"
+		}
+	}
+	useNonce := strings.Contains(conf.ContentSecurityPolicy, NoncePlaceholder)
+	return func(next zjcgo.HandlerFunc) zjcgo.HandlerFunc {
+		return func(ctx *zjcgo.Context) {
+			if len(conf.AllowedHosts) > 0 && !conf.allowed(ctx.R.Host) {
+				conf.BadHostHandler(ctx)
+				return
+			}
+			if conf.SSLRedirect && ctx.R.TLS == nil {
+				host := conf.SSLHost
+				if host == "" {
+					host = ctx.R.Host
+				}
+				target := "https://" + host + ctx.R.URL.RequestURI()
+				code := http.StatusMovedPermanently
+				if ctx.R.Method != http.MethodGet && ctx.R.Method != http.MethodHead {
+					//308保留请求方法和请求体
+					code = http.StatusPermanentRedirect
+				}
+				http.Redirect(ctx.W, ctx.R, target, code)
+				return
+			}
+			header := ctx.W.Header()
+			if sts != "" && ctx.R.TLS != nil {
+				header.Set("Strict-Transport-Security", sts)
+			}
+			if conf.ContentSecurityPolicy != "" {
+				csp := conf.ContentSecurityPolicy
+				if useNonce {
+					nonce := newNonce()
+					ctx.Set(contextKey, nonce)
+					ctx.SetTemplateData(TemplateKey, nonce)
+					csp = strings.ReplaceAll(csp, NoncePlaceholder, "'nonce-"+nonce+"'")
+				}
+				header.Set("Content-Security-Policy", csp)
+			}
+			if conf.FrameOptions != "" {
+				header.Set("X-Frame-Options", conf.FrameOptions)
+			}
+			if conf.ContentTypeNosniff {
+				header.Set("X-Content-Type-Options", "nosniff")
+			}
+			if conf.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", conf.ReferrerPolicy)
+			}
+			if conf.PermissionsPolicy != "" {
+				header.Set("Permissions-Policy", conf.PermissionsPolicy)
+			}
+			next(ctx)
+		}
+	}
+}
+
+//当前请求CSP的nonce，没有时返回空字符串
+func Nonce(ctx *zjcgo.Context) string {
+	if v, ok := ctx.Get(contextKey); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+//模板函数cspNonce，从当前请求的Context里取nonce，要在LoadTemplate之前注册
+func FuncMap() template.FuncMap {
+	return template.FuncMap{TemplateFuncName: zjcgo.ContextFunc(func(ctx *zjcgo.Context) any {
+		return Nonce(ctx)
+	})}
+}
+
+func (conf *Config) allowed(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, allowed := range conf.AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+//用url安全的base64，CSP允许，而且没有+/=，放进html属性不会被转义
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,161 @@
+package secure
+
+import (
+	"crypto/tls"
+	"github.com/BurntSushi/toml"
+	"github.com/zhengjingcheng/zjcgo"
+	"github.com/zhengjingcheng/zjcgo/config"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newEngine(conf Config) *zjcgo.Engine {
+	engine := zjcgo.New()
+	engine.SetHtmlTemplate(template.Must(template.New("page").Parse(`<script nonce="{{.cspNonce}}"></script>`)))
+	engine.Use(New(conf))
+	g := engine.Group("api")
+	g.Any("/page", func(ctx *zjcgo.Context) {
+		_ = ctx.Template("page", nil)
+	})
+	return engine
+}
+
+func TestHSTSOnlyOverTLS(t *testing.T) {
+	conf := DefaultConfig()
+	conf.STSIncludeSubdomains = true
+	engine := newEngine(conf)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/page", nil))
+	if v := w.Header().Get("Strict-Transport-Security"); v != "" {
+		t.Fatalf("HSTS sent over plain http: %q", v)
+	}
+	if w.Header().Get("X-Frame-Options") != "DENY" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("default headers missing: %v", w.Header())
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/page", nil)
+	r.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if v := w.Header().Get("Strict-Transport-Security"); v != "max-age=31536000; includeSubDomains" {
+		t.Fatalf("HSTS over TLS: %q", v)
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	engine := newEngine(Config{AllowedHosts: []string{"example.com"}})
+	for host, want := range map[string]int{
+		"example.com":      http.StatusOK,
+		"EXAMPLE.com:8080": http.StatusOK,
+		"evil.com":         http.StatusBadRequest,
+		"example.com.evil": http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/page", nil)
+		r.Host = host
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("host %s: status %d, want %d", host, w.Code, want)
+		}
+	}
+}
+
+func TestSSLRedirect(t *testing.T) {
+	engine := newEngine(Config{SSLRedirect: true, SSLHost: "secure.example.com"})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/api/page?id=1", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://secure.example.com/api/page?id=1" {
+		t.Fatalf("GET redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	//非GET用308保留方法
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://example.com/api/page", nil))
+	if w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("POST redirect: %d", w.Code)
+	}
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/api/page", nil)
+	r.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("https request: %d", w.Code)
+	}
+}
+
+func TestNonce(t *testing.T) {
+	engine := newEngine(Config{ContentSecurityPolicy: "script-src 'self' $NONCE"})
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/page", nil))
+		csp := w.Header().Get("Content-Security-Policy")
+		if !strings.HasPrefix(csp, "script-src 'self' 'nonce-") || strings.Contains(csp, NoncePlaceholder) {
+			t.Fatalf("csp %q", csp)
+		}
+		nonce := strings.TrimSuffix(strings.TrimPrefix(csp, "script-src 'self' 'nonce-"), "'")
+		//模板里拿到的和响应头里的一致
+		if w.Body.String() != `<script nonce="`+nonce+`"></script>` {
+			t.Fatalf("body %q, nonce %q", w.Body.String(), nonce)
+		}
+		nonces = append(nonces, nonce)
+	}
+	if nonces[0] == nonces[1] {
+		t.Fatal("nonce reused across requests")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	var conf config.ZjcConfig
+	_, err := toml.Decode(`
+[secure]
+allowed_hosts = ["example.com", "www.example.com"]
+ssl_redirect = true
+sts_seconds = 600
+frame_options = "SAMEORIGIN"
+content_type_nosniff = false
+`, &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := config.Conf.Secure
+	config.Conf.Secure = conf.Secure
+	defer func() { config.Conf.Secure = old }()
+
+	c := FromConfig()
+	if len(c.AllowedHosts) != 2 || c.AllowedHosts[1] != "www.example.com" || !c.SSLRedirect {
+		t.Fatalf("hosts %v redirect %v", c.AllowedHosts, c.SSLRedirect)
+	}
+	if c.STSSeconds != 600 || c.FrameOptions != "SAMEORIGIN" || c.ContentTypeNosniff {
+		t.Fatalf("sts %d frame %q nosniff %v", c.STSSeconds, c.FrameOptions, c.ContentTypeNosniff)
+	}
+	//没配置的保持默认值
+	if c.ReferrerPolicy != DefaultConfig().ReferrerPolicy {
+		t.Fatalf("referrer policy %q", c.ReferrerPolicy)
+	}
+}
+
+func TestNonceFuncStructData(t *testing.T) {
+	dir := t.TempDir()
+	tpl := `{{define "page"}}<p>{{.Name}}</p><script nonce="{{cspNonce}}"></script>{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(tpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := zjcgo.New()
+	engine.SetFuncMap(FuncMap())
+	engine.LoadTemplate(filepath.Join(dir, "*.html"))
+	engine.Use(New(Config{ContentSecurityPolicy: "script-src $NONCE"}))
+	g := engine.Group("api")
+	g.Get("/page", func(ctx *zjcgo.Context) {
+		_ = ctx.Template("page", struct{ Name string }{"zjc"})
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/page", nil))
+	nonce := strings.TrimSuffix(strings.TrimPrefix(w.Header().Get("Content-Security-Policy"), "script-src 'nonce-"), "'")
+	if nonce == "" || w.Body.String() != `<p>zjc</p><script nonce="`+nonce+`"></script>` {
+		t.Fatalf("body %q, csp %q", w.Body.String(), w.Header().Get("Content-Security-Policy"))
+	}
+}
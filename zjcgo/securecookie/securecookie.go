@@ -0,0 +1,162 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+/*
+	签名cookie(HMAC-SHA256)和加密cookie(AES-GCM)
+	keys可以传多个：第一个用来签名/加密，验证/解密时逐个尝试，轮换密钥时把新key放在最前面
+	cookie名字参与签名，防止把一个cookie的值拿去冒充另一个
+*/
+
+var (
+	ErrNoKeys    = errors.New("securecookie: no keys")
+	ErrInvalid   = errors.New("securecookie: invalid value")
+	ErrExpired   = errors.New("securecookie: value expired")
+	ErrKeyLength = errors.New("securecookie: aes key must be 16, 24 or 32 bytes")
+)
+
+const timestampSize = 8
+
+var encoding = base64.RawURLEncoding
+
+type Signer struct {
+	keys   [][]byte
+	MaxAge time.Duration //大于0时拒绝签发时间超过MaxAge的值
+}
+
+func NewSigner(keys ...[]byte) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return &Signer{keys: keys}, nil
+}
+
+//value|签发时间 -> base64(时间+value).base64(mac)
+func (s *Signer) Sign(name string, value []byte) string {
+	payload := encoding.EncodeToString(withTimestamp(value))
+	return payload + "." + encoding.EncodeToString(mac(s.keys[0], name, payload))
+}
+
+func (s *Signer) Verify(name string, signed string) ([]byte, error) {
+	index := strings.LastIndexByte(signed, '.')
+	if index < 0 {
+		return nil, ErrInvalid
+	}
+	payload := signed[:index]
+	sig, err := encoding.DecodeString(signed[index+1:])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	ok := false
+	for _, key := range s.keys {
+		if hmac.Equal(sig, mac(key, name, payload)) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return nil, ErrInvalid
+	}
+	data, err := encoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return checkTimestamp(data, s.MaxAge)
+}
+
+type Encrypter struct {
+	aeads  []cipher.AEAD
+	MaxAge time.Duration //大于0时拒绝加密时间超过MaxAge的值
+}
+
+//key长度16、24、32分别对应AES-128、AES-192、AES-256
+func NewEncrypter(keys ...[]byte) (*Encrypter, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	e := &Encrypter{}
+	for _, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, ErrKeyLength
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		e.aeads = append(e.aeads, aead)
+	}
+	return e, nil
+}
+
+//base64(nonce+密文)，cookie名字作为附加数据
+func (e *Encrypter) Encrypt(name string, value []byte) (string, error) {
+	aead := e.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+timestampSize+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, withTimestamp(value), []byte(name))
+	return encoding.EncodeToString(sealed), nil
+}
+
+func (e *Encrypter) Decrypt(name string, encrypted string) ([]byte, error) {
+	data, err := encoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	for _, aead := range e.aeads {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+		nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, sealed, []byte(name))
+		if err == nil {
+			return checkTimestamp(plain, e.MaxAge)
+		}
+	}
+	return nil, ErrInvalid
+}
+
+func mac(key []byte, name string, payload string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(name))
+	h.Write([]byte{'|'})
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func withTimestamp(value []byte) []byte {
+	b := make([]byte, timestampSize, timestampSize+len(value))
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Unix()))
+	return append(b, value...)
+}
+
+func checkTimestamp(data []byte, maxAge time.Duration) ([]byte, error) {
+	if len(data) < timestampSize {
+		return nil, ErrInvalid
+	}
+	if maxAge > 0 {
+		issued := time.Unix(int64(binary.BigEndian.Uint64(data[:timestampSize])), 0)
+		if time.Since(issued) > maxAge {
+			return nil, ErrExpired
+		}
+	}
+	return data[timestampSize:], nil
+}
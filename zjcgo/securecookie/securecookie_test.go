@@ -0,0 +1,41 @@
+package securecookie
+
+import (
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	old, _ := NewSigner([]byte("old"))
+	signed := old.Sign("user", []byte("zjc"))
+	s, _ := NewSigner([]byte("new"), []byte("old"))
+	v, err := s.Verify("user", signed)
+	if err != nil || string(v) != "zjc" {
+		t.Fatalf("verify: %q %v", v, err)
+	}
+	if _, err := s.Verify("admin", signed); err != ErrInvalid {
+		t.Fatalf("signature accepted for another name: %v", err)
+	}
+	if _, err := s.Verify("user", signed[:len(signed)-1]+"A"); err != ErrInvalid {
+		t.Fatalf("tampered value accepted: %v", err)
+	}
+}
+
+func TestEncrypter(t *testing.T) {
+	if _, err := NewEncrypter([]byte("short")); err != ErrKeyLength {
+		t.Fatalf("expected key length error, got %v", err)
+	}
+	oldKey := []byte("0123456789abcdef")
+	old, _ := NewEncrypter(oldKey)
+	encrypted, err := old.Encrypt("user", []byte("zjc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ := NewEncrypter([]byte("fedcba9876543210fedcba9876543210"), oldKey)
+	v, err := e.Decrypt("user", encrypted)
+	if err != nil || string(v) != "zjc" {
+		t.Fatalf("decrypt: %q %v", v, err)
+	}
+	if _, err := e.Decrypt("admin", encrypted); err != ErrInvalid {
+		t.Fatalf("decrypted with another name: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package zjcgo
+
+import (
+	"github.com/zhengjingcheng/zjcgo/sessions"
+	"net/url"
+)
+
+/*
+	会话中间件
+	g.Use(zjcgo.Sessions(sessions.NewMemoryStore(0), nil))
+	s := ctx.Session()
+	s.Set("userId", 1)
+	s.Save()
+*/
+
+//options为nil时用sessions.DefaultOptions()
+func Sessions(store sessions.Store, options *sessions.Options) MiddlewareFunc {
+	if options == nil {
+		options = sessions.DefaultOptions()
+	}
+	if options.CookieName == "" {
+		options.CookieName = sessions.DefaultCookieName
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.sessionStore = store
+			ctx.sessionOptions = options
+			next(ctx)
+		}
+	}
+}
+
+//当前请求的会话，第一次调用时从cookie加载，没有注册Sessions中间件会panic
+func (c *Context) Session() *sessions.Session {
+	if c.session != nil {
+		return c.session
+	}
+	if c.sessionStore == nil {
+		panic("zjcgo: sessions middleware not registered")
+	}
+	options := c.sessionOptions
+	var value string
+	if cookie, err := c.R.Cookie(options.CookieName); err == nil {
+		value, _ = url.QueryUnescape(cookie.Value)
+	}
+	s, err := sessions.Load(c.sessionStore, options, value, func(value string, maxAge int) {
+		sameSite := c.sameSite
+		if options.SameSite != 0 {
+			c.sameSite = options.SameSite
+		}
+		c.SetCookie(options.CookieName, value, maxAge, options.Path, options.Domain, options.Secure, options.HttpOnly)
+		c.sameSite = sameSite
+	})
+	if err != nil && c.Logger != nil {
+		c.Logger.Error("load session: " + err.Error())
+	}
+	c.session = s
+	return s
+}
@@ -0,0 +1,62 @@
+package sessions
+
+import (
+	"errors"
+	"github.com/zhengjingcheng/zjcgo/securecookie"
+	"time"
+)
+
+//cookie最大4096字节，留一点给名字和属性
+const maxCookieSize = 4000
+
+//参与签名的名字，和cookie名字无关
+const cookieStoreName = "zjcgo_session"
+
+var ErrCookieTooLarge = errors.New("sessions: cookie value too large")
+
+//数据签名后整个放在cookie里，不需要服务端存储
+//keys可以有多个，用第一个签名，验证时逐个尝试，方便轮换密钥
+type CookieStore struct {
+	signer *securecookie.Signer
+}
+
+func NewCookieStore(keys ...[]byte) *CookieStore {
+	signer, err := securecookie.NewSigner(keys...)
+	if err != nil {
+		panic(err)
+	}
+	return &CookieStore{signer: signer}
+}
+
+func (c *CookieStore) Load(value string) (string, map[string]any, error) {
+	data, err := c.signer.Verify(cookieStoreName, value)
+	if err != nil {
+		//签名不对当成没有会话
+		return "", nil, nil
+	}
+	r, err := decode(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if r.Expires < time.Now().Unix() {
+		return "", nil, nil
+	}
+	return r.ID, r.Values, nil
+}
+
+func (c *CookieStore) Save(id string, values map[string]any, maxAge int) (string, error) {
+	data, err := encode(&record{ID: id, Expires: time.Now().Unix() + int64(maxAge), Values: values})
+	if err != nil {
+		return "", err
+	}
+	value := c.signer.Sign(cookieStoreName, data)
+	if len(value) > maxCookieSize {
+		return "", ErrCookieTooLarge
+	}
+	return value, nil
+}
+
+//数据在cookie里，过期由cookie负责
+func (c *CookieStore) Delete(id string) error {
+	return nil
+}
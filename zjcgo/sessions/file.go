@@ -0,0 +1,112 @@
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const filePrefix = "sess_"
+
+//每个会话一个文件，多个进程共享一个目录也可以用
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, filePrefix+id)
+}
+
+func (f *FileStore) Load(value string) (string, map[string]any, error) {
+	if !ValidID(value) {
+		return "", nil, nil
+	}
+	data, err := os.ReadFile(f.path(value))
+	if os.IsNotExist(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	r, err := decode(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if r.Expires < time.Now().Unix() {
+		_ = os.Remove(f.path(value))
+		return "", nil, nil
+	}
+	return value, r.Values, nil
+}
+
+func (f *FileStore) Save(id string, values map[string]any, maxAge int) (string, error) {
+	if !ValidID(id) {
+		return "", ErrInvalidID
+	}
+	data, err := encode(&record{ID: id, Expires: time.Now().Unix() + int64(maxAge), Values: values})
+	if err != nil {
+		return "", err
+	}
+	//先写临时文件再改名，避免并发读到一半的数据
+	tmp, err := os.CreateTemp(f.dir, ".tmp_")
+	if err != nil {
+		return "", err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), f.path(id)); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return id, nil
+}
+
+func (f *FileStore) Delete(id string) error {
+	if !ValidID(id) {
+		return ErrInvalidID
+	}
+	err := os.Remove(f.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+//删除过期的会话文件，需要自己定时调用
+func (f *FileStore) Cleanup() error {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), filePrefix) {
+			continue
+		}
+		name := filepath.Join(f.dir, e.Name())
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		r, err := decode(data)
+		if err != nil || r.Expires < now {
+			_ = os.Remove(name)
+		}
+	}
+	return nil
+}
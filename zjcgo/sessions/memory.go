@@ -0,0 +1,105 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	values  map[string]any
+	expires time.Time
+}
+
+//内存存储，后台定时清理过期的会话，进程重启会话就没了
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*memoryEntry
+	stop    chan struct{}
+	once    sync.Once
+}
+
+//interval是清理过期会话的间隔，<=0时默认一分钟
+func NewMemoryStore(interval time.Duration) *MemoryStore {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	m := &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		stop:    make(chan struct{}),
+	}
+	go m.janitor(interval)
+	return m
+}
+
+func (m *MemoryStore) Load(value string) (string, map[string]any, error) {
+	m.mu.RLock()
+	e, ok := m.entries[value]
+	m.mu.RUnlock()
+	if !ok || time.Now().After(e.expires) {
+		return "", nil, nil
+	}
+	return value, copyValues(e.values), nil
+}
+
+func (m *MemoryStore) Save(id string, values map[string]any, maxAge int) (string, error) {
+	m.mu.Lock()
+	m.entries[id] = &memoryEntry{
+		values:  copyValues(values),
+		expires: time.Now().Add(time.Duration(maxAge) * time.Second),
+	}
+	m.mu.Unlock()
+	return id, nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	delete(m.entries, id)
+	m.mu.Unlock()
+	return nil
+}
+
+//当前会话数量
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.entries)
+}
+
+//停止后台清理
+func (m *MemoryStore) Close() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
+
+func (m *MemoryStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.deleteExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *MemoryStore) deleteExpired() {
+	now := time.Now()
+	m.mu.Lock()
+	for id, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, id)
+		}
+	}
+	m.mu.Unlock()
+}
+
+func copyValues(values map[string]any) map[string]any {
+	c := make(map[string]any, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
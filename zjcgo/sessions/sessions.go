@@ -0,0 +1,179 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"regexp"
+)
+
+/*
+	服务端会话，数据放在Store里，cookie里只放会话id(签名cookie存储除外，数据整个放在cookie里)
+	值用gob编码，自定义类型要先调用 sessions.Register 注册
+*/
+
+const DefaultCookieName = "zjcgo_session"
+
+var ErrInvalidID = errors.New("sessions: invalid session id")
+
+//会话的存储
+type Store interface {
+	//根据cookie的值取出会话，不存在或已过期时返回空id和nil
+	Load(value string) (id string, values map[string]any, err error)
+	//保存会话，返回要写进cookie的值，maxAge单位秒
+	Save(id string, values map[string]any, maxAge int) (value string, err error)
+	Delete(id string) error
+}
+
+type Options struct {
+	CookieName string
+	Path       string
+	Domain     string
+	MaxAge     int //秒，默认一天
+	Secure     bool
+	HttpOnly   bool
+	SameSite   http.SameSite //为0时用Context.SetSameSite的设置
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		CookieName: DefaultCookieName,
+		Path:       "/",
+		MaxAge:     86400,
+		HttpOnly:   true,
+	}
+}
+
+//写cookie的函数，由框架传进来
+type CookieWriter func(value string, maxAge int)
+
+type Session struct {
+	id      string
+	oldID   string //RegenerateID之前的id，保存时从store删除
+	values  map[string]any
+	store   Store
+	options *Options
+	isNew   bool
+	writer  CookieWriter
+}
+
+//从cookie值加载会话，加载不到就新建一个
+func Load(store Store, options *Options, value string, writer CookieWriter) (*Session, error) {
+	s := &Session{store: store, options: options, writer: writer}
+	var err error
+	if value != "" {
+		s.id, s.values, err = store.Load(value)
+	}
+	if s.id == "" {
+		s.id = NewID()
+		s.values = make(map[string]any)
+		s.isNew = true
+	}
+	if s.values == nil {
+		s.values = make(map[string]any)
+	}
+	return s, err
+}
+
+func (s *Session) ID() string {
+	return s.id
+}
+
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+func (s *Session) Get(key string) any {
+	return s.values[key]
+}
+
+func (s *Session) Set(key string, value any) {
+	s.values[key] = value
+}
+
+func (s *Session) Delete(key string) {
+	delete(s.values, key)
+}
+
+//清空所有数据，保存时会删除会话并让cookie过期
+func (s *Session) Clear() {
+	s.values = make(map[string]any)
+}
+
+//换一个新的会话id，数据不变，登录成功后要调用防止会话固定攻击
+func (s *Session) RegenerateID() {
+	if s.oldID == "" && !s.isNew {
+		s.oldID = s.id
+	}
+	s.id = NewID()
+}
+
+//保存到store并写cookie，必须在写响应体之前调用
+func (s *Session) Save() error {
+	if s.oldID != "" {
+		if err := s.store.Delete(s.oldID); err != nil {
+			return err
+		}
+		s.oldID = ""
+	}
+	if len(s.values) == 0 {
+		if err := s.store.Delete(s.id); err != nil {
+			return err
+		}
+		s.writer("", -1)
+		return nil
+	}
+	value, err := s.store.Save(s.id, s.values, s.options.MaxAge)
+	if err != nil {
+		return err
+	}
+	s.isNew = false
+	s.writer(value, s.options.MaxAge)
+	return nil
+}
+
+//注册会话里要存的自定义类型
+func Register(value any) {
+	gob.Register(value)
+}
+
+//生成随机的会话id
+func NewID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+//id只能是NewID生成的字符，防止拼到文件路径里
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type record struct {
+	ID      string
+	Expires int64
+	Values  map[string]any
+}
+
+func encode(r *record) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte) (*record, error) {
+	r := &record{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,73 @@
+package sessions
+
+import (
+	"testing"
+)
+
+func TestCookieStore(t *testing.T) {
+	old := NewCookieStore([]byte("old-key"))
+	value, err := old.Save("abc", map[string]any{"userId": 1}, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//新密钥放前面，旧cookie还能验证
+	store := NewCookieStore([]byte("new-key"), []byte("old-key"))
+	id, values, err := store.Load(value)
+	if err != nil || id != "abc" || values["userId"] != 1 {
+		t.Fatalf("load: %v %v %v", id, values, err)
+	}
+	if id, _, _ := NewCookieStore([]byte("other")).Load(value); id != "" {
+		t.Fatal("cookie signed with unknown key accepted")
+	}
+	if id, _, _ := store.Load(value[:len(value)-2] + "xx"); id != "" {
+		t.Fatal("tampered cookie accepted")
+	}
+}
+
+func TestSession(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cookie string
+	var cookieAge int
+	writer := func(value string, maxAge int) {
+		cookie, cookieAge = value, maxAge
+	}
+	s, _ := Load(store, DefaultOptions(), "", writer)
+	if !s.IsNew() {
+		t.Fatal("expected new session")
+	}
+	s.Set("name", "zjc")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	first := cookie
+
+	s, _ = Load(store, DefaultOptions(), first, writer)
+	if s.IsNew() || s.Get("name") != "zjc" {
+		t.Fatalf("session not loaded: %v", s.Get("name"))
+	}
+	s.RegenerateID()
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if cookie == first {
+		t.Fatal("id not regenerated")
+	}
+	if id, _, _ := store.Load(first); id != "" {
+		t.Fatal("old session still in store")
+	}
+
+	s, _ = Load(store, DefaultOptions(), cookie, writer)
+	s.Clear()
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if cookieAge != -1 {
+		t.Fatalf("cookie not expired: %d", cookieAge)
+	}
+	if _, _, err := store.Load("../../etc/passwd"); err != nil {
+		t.Fatal(err)
+	}
+}
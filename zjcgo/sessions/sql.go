@@ -0,0 +1,89 @@
+package sessions
+
+import (
+	"fmt"
+	"github.com/zhengjingcheng/zjcgo/orm"
+	"time"
+)
+
+/*
+	数据库存储，表结构(mysql)：
+	CREATE TABLE zjc_session (
+		id varchar(64) NOT NULL PRIMARY KEY,
+		data blob NOT NULL,
+		expires bigint NOT NULL,
+		KEY idx_expires (expires)
+	)
+*/
+
+const DefaultTableName = "zjc_session"
+
+type sessionRow struct {
+	Id      string `zjcorm:"id"`
+	Data    []byte `zjcorm:"data"`
+	Expires int64  `zjcorm:"expires"`
+}
+
+type SQLStore struct {
+	db    *orm.ZjcDb
+	table string
+}
+
+//table为空时用zjc_session
+func NewSQLStore(db *orm.ZjcDb, table string) *SQLStore {
+	if table == "" {
+		table = DefaultTableName
+	}
+	return &SQLStore{db: db, table: table}
+}
+
+func (s *SQLStore) Load(value string) (string, map[string]any, error) {
+	if !ValidID(value) {
+		return "", nil, nil
+	}
+	row := &sessionRow{}
+	err := s.db.New(row).Table(s.table).Where("id", value).SelectOne(row)
+	if err != nil {
+		return "", nil, err
+	}
+	if row.Id == "" || row.Expires < time.Now().Unix() {
+		return "", nil, nil
+	}
+	r, err := decode(row.Data)
+	if err != nil {
+		return "", nil, err
+	}
+	return value, r.Values, nil
+}
+
+func (s *SQLStore) Save(id string, values map[string]any, maxAge int) (string, error) {
+	if !ValidID(id) {
+		return "", ErrInvalidID
+	}
+	expires := time.Now().Unix() + int64(maxAge)
+	data, err := encode(&record{ID: id, Expires: expires, Values: values})
+	if err != nil {
+		return "", err
+	}
+	query := fmt.Sprintf("replace into %s (id,data,expires) values (?,?,?)", s.table)
+	_, err = s.db.New(&sessionRow{}).Table(s.table).Exec(query, id, data, expires)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *SQLStore) Delete(id string) error {
+	if !ValidID(id) {
+		return ErrInvalidID
+	}
+	_, err := s.db.New(&sessionRow{}).Table(s.table).Where("id", id).Delete()
+	return err
+}
+
+//删除过期的会话，需要自己定时调用
+func (s *SQLStore) Cleanup() error {
+	query := fmt.Sprintf("delete from %s where expires < ?", s.table)
+	_, err := s.db.New(&sessionRow{}).Table(s.table).Exec(query, time.Now().Unix())
+	return err
+}
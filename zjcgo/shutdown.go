@@ -0,0 +1,51 @@
+package zjcgo
+
+import (
+	"context"
+	"net/http"
+)
+
+/*
+	优雅退出
+	go engine.Run(":8080")
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	engine.Shutdown(ctx)
+	Shutdown以后Run返回，不会log.Fatal
+*/
+
+func (e *Engine) newServer(addr string) *http.Server {
+	e.serverMu.Lock()
+	defer e.serverMu.Unlock()
+	e.server = &http.Server{Addr: addr, Handler: e.Handler()}
+	return e.server
+}
+
+//Shutdown时执行，后注册的先执行，比如关数据库连接、停协程池
+func (e *Engine) OnShutdown(fn func()) {
+	e.serverMu.Lock()
+	e.shutdownHooks = append(e.shutdownHooks, fn)
+	e.serverMu.Unlock()
+}
+
+//不再接收新请求，等处理中的请求结束，执行OnShutdown注册的函数，最后把日志写完关掉
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.serverMu.Lock()
+	server := e.server
+	hooks := e.shutdownHooks
+	e.shutdownHooks = nil
+	e.serverMu.Unlock()
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	if e.Logger != nil {
+		e.Logger.Flush()
+		e.Logger.CloseWriter()
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+package zjcgo
+
+import (
+	"github.com/zhengjingcheng/zjcgo/storage"
+)
+
+//把Storage里的对象挂到prefix下面，支持Range和ETag，secret不为空时只接受签名过的地址
+//g.ServeStorage("/files", store, nil)  ->  GET /group/files/avatar/xxx.png
+func (r *router) ServeStorage(prefix string, s storage.Storage, secret []byte, middlewareFunc ...MiddlewareFunc) {
+	r.Mount(prefix, storage.Handler(s, secret), middlewareFunc...)
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+//按请求路径(去掉开头的/)取对象返回，支持Range、If-None-Match、If-Modified-Since
+//secret不为空时要求带SignedURL生成的签名
+func Handler(s Storage, secret []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		key, err := CleanKey(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if len(secret) > 0 && !VerifyURL(key, r.URL.Query(), secret) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		rc, obj, err := s.Get(r.Context(), key)
+		if errors.Is(err, ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer rc.Close()
+		if obj.ETag != "" {
+			w.Header().Set("ETag", obj.ETag)
+		}
+		contentType := obj.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		//html、svg这类能执行脚本的只能下载，不能在我们的域名下直接打开
+		if !InlineType(contentType) {
+			w.Header().Set("Content-Disposition", "attachment")
+		}
+		//ServeContent负责Range和条件请求
+		http.ServeContent(w, r, "", obj.ModTime, rc)
+	})
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//本地文件系统存储，key对应root下的相对路径
+type Local struct {
+	root    string
+	baseURL string //SignedURL用的地址前缀，比如 http://localhost:8080/files
+	secret  []byte
+}
+
+//baseURL和secret为空时不支持SignedURL
+func NewLocal(root string, baseURL string, secret []byte) (*Local, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &Local{root: root, baseURL: baseURL, secret: secret}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+//先写同目录下的临时文件再改名，读的人不会看到写了一半的文件
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".put_")
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	obj, err := l.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	//Get的时候按扩展名判断类型，这里也一样，扩展名认不出来才用传进来的
+	if obj.ContentType == "" {
+		obj.ContentType = contentType
+	}
+	obj.Size = size
+	return obj, nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (ReadSeekCloser, *Object, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, nil, ErrNotExist
+	}
+	return f, fileObject(key, info), nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (*Object, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotExist
+	}
+	return fileObject(key, info), nil
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]*Object, error) {
+	objects := make([]*Object, 0)
+	err := filepath.WalkDir(l.root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".put_") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObject(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+func (l *Local) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if l.baseURL == "" || len(l.secret) == 0 {
+		return "", ErrSignNotConfigured
+	}
+	if _, err := CleanKey(key); err != nil {
+		return "", err
+	}
+	return SignURL(l.baseURL, key, l.secret, time.Now().Add(expires)), nil
+}
+
+//本地文件没有存内容hash，ETag用大小和修改时间，Put、Get、Stat返回的都一样
+func fileObject(key string, info fs.FileInfo) *Object {
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ETag:        fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+		ModTime:     info.ModTime(),
+	}
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data []byte
+	obj  Object
+}
+
+//内存存储，测试或者小文件用
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+	baseURL string
+	secret  []byte
+}
+
+func NewMemory(baseURL string, secret []byte) *Memory {
+	return &Memory{objects: make(map[string]*memoryObject), baseURL: baseURL, secret: secret}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	o := &memoryObject{
+		data: data,
+		obj: Object{
+			Key:         key,
+			Size:        int64(len(data)),
+			ContentType: contentType,
+			ETag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+			ModTime:     time.Now(),
+		},
+	}
+	m.mu.Lock()
+	m.objects[key] = o
+	m.mu.Unlock()
+	obj := o.obj
+	return &obj, nil
+}
+
+//bytes.Reader没有Close
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (ReadSeekCloser, *Object, error) {
+	m.mu.RLock()
+	o, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrNotExist
+	}
+	obj := o.obj
+	return nopCloser{bytes.NewReader(o.data)}, &obj, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Stat(ctx context.Context, key string) (*Object, error) {
+	m.mu.RLock()
+	o, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotExist
+	}
+	obj := o.obj
+	return &obj, nil
+}
+
+func (m *Memory) List(ctx context.Context, prefix string) ([]*Object, error) {
+	m.mu.RLock()
+	objects := make([]*Object, 0)
+	for key, o := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			obj := o.obj
+			objects = append(objects, &obj)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+func (m *Memory) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if m.baseURL == "" || len(m.secret) == 0 {
+		return "", ErrSignNotConfigured
+	}
+	if _, err := CleanKey(key); err != nil {
+		return "", err
+	}
+	return SignURL(m.baseURL, key, m.secret, time.Now().Add(expires)), nil
+}
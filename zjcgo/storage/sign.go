@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//下载地址签名：baseURL/key?expires=时间戳&signature=hmac(key+expires)
+func SignURL(baseURL string, key string, secret []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", signature(secret, key, exp))
+	u := strings.TrimRight(baseURL, "/") + "/" + (&url.URL{Path: key}).EscapedPath()
+	return u + "?" + q.Encode()
+}
+
+//检查签名和有没有过期
+func VerifyURL(key string, query url.Values, secret []byte) bool {
+	exp := query.Get("expires")
+	t, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > t {
+		return false
+	}
+	sig, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(signature(secret, key, exp))
+	return hmac.Equal(sig, want)
+}
+
+func signature(secret []byte, key string, expires string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(key))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(expires))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+	文件存储，上传的文件不直接写本地磁盘，而是交给Storage，方便以后换成对象存储
+	obj, err := storage.PutFile(ctx, store, "avatar/", file, "image/*")
+*/
+
+var (
+	ErrNotExist           = errors.New("storage: object not exist")
+	ErrInvalidKey         = errors.New("storage: invalid key")
+	ErrSignNotConfigured  = errors.New("storage: signed url not configured")
+	ErrFileTypeNotAllowed = errors.New("storage: file type not allowed")
+)
+
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ETag        string //带引号，可以直接放到响应头里
+	ModTime     time.Time
+}
+
+type ReadSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+type Storage interface {
+	//保存对象，同名的会被覆盖
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error)
+	//返回的reader可以Seek，支持Range请求
+	Get(ctx context.Context, key string) (ReadSeekCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	//不存在返回ErrNotExist
+	Stat(ctx context.Context, key string) (*Object, error)
+	//列出prefix开头的对象，按key排序
+	List(ctx context.Context, prefix string) ([]*Object, error)
+	//带过期时间的下载地址
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+//key用/分隔，不能是绝对路径，不能有..和空的段
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, "\\\x00") || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+//按内容的sha256命名保存上传的文件，内容相同的文件只存一份
+//类型按内容判断，allowedTypes支持 image/png 和 image/* 两种写法，为空不检查
+func PutFile(ctx context.Context, s Storage, prefix string, file *multipart.FileHeader, allowedTypes ...string) (*Object, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	//multipart.File可以Seek，先读一遍算hash，不用另存临时文件
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !MatchType(contentType, allowedTypes) {
+		return nil, ErrFileTypeNotAllowed
+	}
+	h := sha256.New()
+	h.Write(head[:n])
+	if _, err := io.Copy(h, src); err != nil {
+		return nil, err
+	}
+	//扩展名按内容类型来，不用客户端的文件名，不然png+html的文件起名x.html就能当网页打开
+	key := prefix + hex.EncodeToString(h.Sum(nil)) + extByType(contentType)
+	if obj, err := s.Stat(ctx, key); err == nil {
+		return obj, nil
+	} else if !errors.Is(err, ErrNotExist) {
+		return nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.Put(ctx, key, src, contentType)
+}
+
+//contentType可以带参数，比如text/html; charset=utf-8
+func MatchType(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	for _, a := range allowed {
+		if a == contentType {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(contentType, a[:len(a)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+//常见类型固定扩展名，其他的用mime包里的第一个
+var typeExts = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"image/x-icon":    ".ico",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+}
+
+func extByType(contentType string) string {
+	mediaType := contentType
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	if ext, ok := typeExts[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+//浏览器里直接打开也不会执行脚本的类型，其他的都按附件下载
+func InlineType(contentType string) bool {
+	mediaType := contentType
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case mediaType == "text/plain":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanKey(t *testing.T) {
+	for _, key := range []string{"", "/a", "a/../b", "a//b", `a\b`, ".."} {
+		if _, err := CleanKey(key); err != ErrInvalidKey {
+			t.Errorf("%q should be invalid", key)
+		}
+	}
+	if _, err := CleanKey("avatar/a.png"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	m := NewMemory("http://localhost/files", []byte("secret"))
+	if _, err := m.Put(context.Background(), "a/b.txt", strings.NewReader("hello"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := m.SignedURL(context.Background(), "a/b.txt", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(raw)
+	if !VerifyURL("a/b.txt", u.Query(), []byte("secret")) {
+		t.Fatal("valid signature rejected")
+	}
+	if VerifyURL("a/c.txt", u.Query(), []byte("secret")) {
+		t.Fatal("signature accepted for another key")
+	}
+}
+
+//png文件头后面跟html，起名x.html上传
+func TestPutFilePolyglot(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "x.html")
+	fw.Write([]byte("\x89PNG\r\n\x1a\n<html><script>alert(1)</script></html>"))
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewLocal(t.TempDir(), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := PutFile(context.Background(), store, "img/", r.MultipartForm.File["file"][0], "image/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(obj.Key, ".png") {
+		t.Fatalf("key %q should use the sniffed extension", obj.Key)
+	}
+	stat, err := store.Stat(context.Background(), obj.Key)
+	if err != nil || stat.ETag != obj.ETag {
+		t.Fatalf("etag from Put %s, Stat %v %v", obj.ETag, stat, err)
+	}
+
+	w := httptest.NewRecorder()
+	Handler(store, nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+obj.Key, nil))
+	if w.Header().Get("Content-Type") != "image/png" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("served as %q", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Fatal("images should be inline")
+	}
+
+	//不是图片之类的类型只能下载
+	store.Put(context.Background(), "page.html", strings.NewReader("<html></html>"), "text/html")
+	w = httptest.NewRecorder()
+	Handler(store, nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page.html", nil))
+	if w.Header().Get("Content-Disposition") != "attachment" {
+		t.Fatalf("html served inline: %v", w.Header())
+	}
+}
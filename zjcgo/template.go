@@ -0,0 +1,87 @@
+package zjcgo
+
+import (
+	"html/template"
+	"sync"
+)
+
+/*
+	模板里用到当前请求的函数，比如csrfToken、cspNonce，模板拿到的数据是什么类型都能用
+	engine.SetFuncMap(template.FuncMap{"userName": zjcgo.ContextFunc(func(ctx *zjcgo.Context) any {
+		return ctx.GetString("user")
+	})})
+	engine.LoadTemplate("tpl/*.html")
+	模板里：{{userName}}
+	模板只解析一次，克隆出来的副本放在池子里复用，执行前把副本的函数绑定到当前的Context
+*/
+
+type ContextFunc func(ctx *Context) any
+
+//解析模板用的函数表，ContextFunc换成不带参数的占位函数
+func (e *Engine) parseFuncMap() template.FuncMap {
+	funcMap := make(template.FuncMap, len(e.funcMap))
+	for name, fn := range e.funcMap {
+		if _, ok := fn.(ContextFunc); ok {
+			funcMap[name] = func() any { return "" }
+			continue
+		}
+		funcMap[name] = fn
+	}
+	return funcMap
+}
+
+func (e *Engine) contextFuncs() map[string]ContextFunc {
+	funcs := make(map[string]ContextFunc)
+	for name, fn := range e.funcMap {
+		if f, ok := fn.(ContextFunc); ok {
+			funcs[name] = f
+		}
+	}
+	return funcs
+}
+
+type contextTemplate struct {
+	t   *template.Template
+	ctx *Context
+}
+
+type contextTemplates struct {
+	base  *template.Template //没执行过的模板，只用来克隆
+	funcs map[string]ContextFunc
+	pool  sync.Pool
+}
+
+func newContextTemplates(t *template.Template, funcs map[string]ContextFunc) *contextTemplates {
+	//执行过的模板不能再Clone，先留一份
+	base, err := t.Clone()
+	if err != nil {
+		return nil
+	}
+	return &contextTemplates{base: base, funcs: funcs}
+}
+
+func (ct *contextTemplates) get(c *Context) (*contextTemplate, error) {
+	entry, _ := ct.pool.Get().(*contextTemplate)
+	if entry == nil {
+		t, err := ct.base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		entry = &contextTemplate{}
+		funcMap := make(template.FuncMap, len(ct.funcs))
+		for name, fn := range ct.funcs {
+			fn := fn
+			funcMap[name] = func() any {
+				return fn(entry.ctx)
+			}
+		}
+		entry.t = t.Funcs(funcMap)
+	}
+	entry.ctx = c
+	return entry, nil
+}
+
+func (ct *contextTemplates) put(entry *contextTemplate) {
+	entry.ctx = nil
+	ct.pool.Put(entry)
+}
@@ -10,6 +10,15 @@ import (
 
 const JWTToken = "zjcgo_token"
 
+//cookie里token的保存方式
+type CookieMode int
+
+const (
+	CookiePlain     CookieMode = iota //原样保存
+	CookieSigned                      //签名，需要engine.SetCookieSigningKeys
+	CookieEncrypted                   //加密，需要engine.SetCookieEncryptionKeys，客户端看不到claims
+)
+
 type JwtHandler struct {
 	//jwt算法
 	Alg string
@@ -34,6 +43,7 @@ type JwtHandler struct {
 	CookieDomain   string
 	SecureCookie   bool
 	CookieHTTPOnly bool
+	CookieMode     CookieMode
 	Header         string
 	AuthHandler    func(ctx *zjcgo.Context, err error)
 }
@@ -110,7 +120,9 @@ func (j *JwtHandler) LoginHandler(ctx *zjcgo.Context) (*JwtResponse, error) {
 		if j.CookieMaxAge == 0 {
 			j.CookieMaxAge = expire.Unix() - j.TimeFun().Unix()
 		}
-		ctx.SetCookie(j.CookieName, tokenString, int(j.CookieMaxAge), "/", j.CookieDomain, j.SecureCookie, j.CookieHTTPOnly)
+		if err := j.setCookie(ctx, tokenString); err != nil {
+			return nil, err
+		}
 	}
 	//refreshToken类似token生成
 	refreshToken, err := j.refreshToken(token)
@@ -149,6 +161,36 @@ func (j *JwtHandler) refreshToken(token *jwt.Token) (string, error) {
 	return tokenString, nil
 }
 
+func (j *JwtHandler) setCookie(ctx *zjcgo.Context, token string) error {
+	switch j.CookieMode {
+	case CookieSigned:
+		return ctx.SetSignedCookie(j.CookieName, token, int(j.CookieMaxAge), "/", j.CookieDomain, j.SecureCookie, j.CookieHTTPOnly)
+	case CookieEncrypted:
+		return ctx.SetEncryptedCookie(j.CookieName, token, int(j.CookieMaxAge), "/", j.CookieDomain, j.SecureCookie, j.CookieHTTPOnly)
+	}
+	ctx.SetCookie(j.CookieName, token, int(j.CookieMaxAge), "/", j.CookieDomain, j.SecureCookie, j.CookieHTTPOnly)
+	return nil
+}
+
+//签名或者解密失败当成没有token
+//中间件是并发调用的，这里只读不改CookieName
+func (j *JwtHandler) getCookie(ctx *zjcgo.Context) string {
+	name := j.CookieName
+	if name == "" {
+		name = JWTToken
+	}
+	var token string
+	switch j.CookieMode {
+	case CookieSigned:
+		token, _ = ctx.GetSignedCookie(name)
+	case CookieEncrypted:
+		token, _ = ctx.GetEncryptedCookie(name)
+	default:
+		token = ctx.GetCookie(name)
+	}
+	return token
+}
+
 //退出登录
 func (j *JwtHandler) LogoutHandler(ctx *zjcgo.Context) error {
 	//如果有cookie就将cookie删掉
@@ -229,7 +271,9 @@ func (j *JwtHandler) RefreshHandler(ctx *zjcgo.Context) (*JwtResponse, error) {
 		if j.CookieMaxAge == 0 {
 			j.CookieMaxAge = expire.Unix() - j.TimeFun().Unix()
 		}
-		ctx.SetCookie(j.CookieName, tokenString, int(j.CookieMaxAge), "/", j.CookieDomain, j.SecureCookie, j.CookieHTTPOnly)
+		if err := j.setCookie(ctx, tokenString); err != nil {
+			return nil, err
+		}
 	}
 	//refreshToken类似token生成
 	refreshToken, err := j.refreshToken(t)
@@ -253,7 +297,7 @@ func (j *JwtHandler) AuthInterceptor(next zjcgo.HandlerFunc) zjcgo.HandlerFunc {
 		if token == "" {
 			//从缓冲中获得
 			if j.SendCookie {
-				token = ctx.GetCookie(j.CookieName)
+				token = j.getCookie(ctx)
 				if token == "" {
 					if j.AuthHandler == nil {
 						ctx.W.WriteHeader(http.StatusUnauthorized)
@@ -0,0 +1,60 @@
+package zjcgo
+
+import (
+	"errors"
+	zjcLog "github.com/zhengjingcheng/zjcgo/log"
+	"github.com/zhengjingcheng/zjcgo/trace"
+	"net/http"
+)
+
+/*
+	链路追踪中间件，每个请求开启一个server span，上游通过traceparent头传过来的会接上
+*/
+
+type TraceConfig struct {
+	Tracer *trace.Tracer //默认 trace.DefaultTracer()
+}
+
+func TraceWithConfig(conf TraceConfig) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return traceHandle(conf.Tracer, next)
+	}
+}
+
+func Trace(next HandlerFunc) HandlerFunc {
+	return traceHandle(nil, next)
+}
+
+func traceHandle(tracer *trace.Tracer, next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		t := tracer
+		if t == nil {
+			t = trace.DefaultTracer()
+		}
+		parent := trace.Extract(ctx.R.Context(), trace.HeaderCarrier(ctx.R.Header))
+		route := ctx.FullPath()
+		c, span := t.Start(parent, ctx.R.Method+" "+route, trace.WithKind(trace.KindServer))
+		defer span.End()
+		span.SetAttribute("http.method", ctx.R.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", ctx.R.URL.RequestURI())
+		if id := ctx.RequestID(); id != "" {
+			span.SetAttribute("request_id", id)
+		}
+		if ctx.Logger != nil {
+			sc := span.SpanContext()
+			ctx.Logger = ctx.Logger.With("trace_id", sc.TraceID.String(), "span_id", sc.SpanID.String())
+			c = zjcLog.IntoContext(c, ctx.Logger)
+		}
+		ctx.R = ctx.R.WithContext(c)
+		next(ctx)
+		status := ctx.StatusCode
+		if w, ok := ctx.W.(ResponseWriter); ok {
+			status = w.Status()
+		}
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.RecordError(errors.New(http.StatusText(status)))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+/*
+	W3C traceparent 编解码
+	格式: version-traceid-spanid-flags 例如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+*/
+
+const (
+	TraceparentHeader = "traceparent"
+	supportedVersion  = 0
+)
+
+var ErrInvalidTraceparent = errors.New("trace: invalid traceparent")
+
+func FormatTraceparent(sc SpanContext) string {
+	var sb strings.Builder
+	sb.WriteString("00-")
+	sb.WriteString(sc.TraceID.String())
+	sb.WriteString("-")
+	sb.WriteString(sc.SpanID.String())
+	sb.WriteString("-")
+	sb.WriteString(hex.EncodeToString([]byte{sc.Flags}))
+	return sb.String()
+}
+
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceparent
+	}
+	version, err := decodeLower(parts[0])
+	if err != nil || version[0] == 0xff {
+		return sc, ErrInvalidTraceparent
+	}
+	//version 00 只能有4段，更高的版本向前兼容
+	if version[0] == supportedVersion && len(parts) != 4 {
+		return sc, ErrInvalidTraceparent
+	}
+	traceID, err := decodeLower(parts[1])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	spanID, err := decodeLower(parts[2])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	flags, err := decodeLower(parts[3])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+//规范要求只能是小写十六进制
+func decodeLower(s string) ([]byte, error) {
+	if strings.ToLower(s) != s {
+		return nil, ErrInvalidTraceparent
+	}
+	return hex.DecodeString(s)
+}
+
+//span信息的载体，http头、rpc元数据都可以
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+type HeaderCarrier http.Header
+
+func (c HeaderCarrier) Get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+func (c HeaderCarrier) Set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+type MapCarrier map[string]string
+
+func (c MapCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+//把当前span信息写进载体
+func Inject(ctx context.Context, carrier Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(TraceparentHeader, FormatTraceparent(sc))
+}
+
+//从载体中解析上游的span信息放进ctx
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	sc, err := ParseTraceparent(carrier.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
@@ -0,0 +1,264 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+/*
+	分布式链路追踪，一次请求是一条trace，经过的每个环节是一个span
+*/
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+const FlagSampled byte = 0x01
+
+//span的身份信息，跨进程传递的就是这部分
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	Remote  bool //是否是从上游解析出来的
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagSampled == FlagSampled
+}
+
+type SpanKind string
+
+const (
+	KindInternal SpanKind = "internal"
+	KindServer   SpanKind = "server"
+	KindClient   SpanKind = "client"
+)
+
+type Span struct {
+	tracer     *Tracer
+	mu         sync.Mutex
+	name       string
+	kind       SpanKind
+	sc         SpanContext
+	parent     SpanID
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	err        string
+	ended      bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]any)
+	}
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+//记录错误，span的状态会变成error
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err.Error()
+	s.mu.Unlock()
+}
+
+//结束span并导出，重复调用只生效一次
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	data := s.snapshot()
+	s.mu.Unlock()
+	if s.sc.IsSampled() && s.tracer.exporter != nil {
+		_ = s.tracer.exporter.Export(data)
+	}
+}
+
+func (s *Span) snapshot() *SpanData {
+	attributes := make(map[string]any, len(s.attributes))
+	for k, v := range s.attributes {
+		attributes[k] = v
+	}
+	data := &SpanData{
+		Service:    s.tracer.service,
+		Name:       s.name,
+		Kind:       s.kind,
+		TraceID:    s.sc.TraceID.String(),
+		SpanID:     s.sc.SpanID.String(),
+		Start:      s.start,
+		End:        s.end,
+		Duration:   s.end.Sub(s.start),
+		Attributes: attributes,
+		Error:      s.err,
+	}
+	if s.parent.IsValid() {
+		data.ParentSpanID = s.parent.String()
+	}
+	return data
+}
+
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+var (
+	defaultTracer = NewTracer("zjcgo", nil)
+	defaultMu     sync.RWMutex
+)
+
+//框架内置的埋点（http、rpc、orm）都使用默认的tracer，没有设置exporter时不导出
+func DefaultTracer() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracer
+}
+
+func SetDefaultTracer(t *Tracer) {
+	defaultMu.Lock()
+	defaultTracer = t
+	defaultMu.Unlock()
+}
+
+type StartOption func(s *Span)
+
+func WithKind(kind SpanKind) StartOption {
+	return func(s *Span) {
+		s.kind = kind
+	}
+}
+
+func WithAttributes(attributes map[string]any) StartOption {
+	return func(s *Span) {
+		for k, v := range attributes {
+			s.SetAttribute(k, v)
+		}
+	}
+}
+
+//开启一个span，ctx中有父span（或者上游传过来的span）的话作为它的子span
+func (t *Tracer) Start(ctx context.Context, name string, ops ...StartOption) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &Span{tracer: t, name: name, kind: KindInternal, start: time.Now()}
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.sc.Flags = parent.Flags
+		s.parent = parent.SpanID
+	} else {
+		s.sc.TraceID = newTraceID()
+		s.sc.Flags = FlagSampled
+	}
+	s.sc.SpanID = newSpanID()
+	for _, op := range ops {
+		op(s)
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+//上游传过来的span信息
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+//当前生效的span信息，优先取本进程的span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	s := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.IsSampled() || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if got := FormatTraceparent(sc); got != s {
+		t.Fatalf("format got %s", got)
+	}
+	for _, bad := range []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	exporter := NewMemoryExporter()
+	tracer := NewTracer("test", exporter)
+
+	ctx, root := tracer.Start(context.Background(), "client", WithKind(KindClient))
+	header := http.Header{}
+	Inject(ctx, HeaderCarrier(header))
+	root.End()
+
+	//另一端解析出来继续往下
+	remote := Extract(context.Background(), HeaderCarrier(header))
+	ctx, server := tracer.Start(remote, "server", WithKind(KindServer))
+	_, child := tracer.Start(ctx, "orm select")
+	child.End()
+	server.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for _, s := range spans {
+		if s.TraceID != root.SpanContext().TraceID.String() {
+			t.Errorf("span %s has trace id %s", s.Name, s.TraceID)
+		}
+	}
+	if spans[1].Name != "orm select" || spans[1].ParentSpanID != server.SpanContext().SpanID.String() {
+		t.Errorf("child span parent mismatch: %+v", spans[1])
+	}
+	if spans[2].ParentSpanID != root.SpanContext().SpanID.String() {
+		t.Errorf("server span parent mismatch: %+v", spans[2])
+	}
+}
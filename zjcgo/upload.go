@@ -0,0 +1,190 @@
+package zjcgo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zhengjingcheng/zjcgo/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+/*
+	请求体大小限制和文件上传
+	engine.MaxBodySize = 10 << 20
+	g.Post("/upload", handler, zjcgo.BodyLimit(100<<20))
+	path, err := ctx.SaveUploadedFileTo(file, "./upload", "image/*")
+*/
+
+var (
+	ErrBodyTooLarge       = errors.New("zjcgo: request body too large")
+	ErrFileTypeNotAllowed = errors.New("zjcgo: file type not allowed")
+)
+
+const maxFilenameLength = 255
+
+//单个路由的请求体大小限制，覆盖Engine.MaxBodySize
+func BodyLimit(n int64) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.maxBodySize = n
+			next(ctx)
+		}
+	}
+}
+
+//设置当前请求解析multipart表单时的内存大小
+func (c *Context) SetMultipartMemory(n int64) {
+	c.multipartMemory = n
+}
+
+//Content-Length已经超过限制的直接返回413
+func checkBodySize(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		if ctx.maxBodySize > 0 && ctx.R.ContentLength > ctx.maxBodySize {
+			ctx.bodyTooLarge()
+			return
+		}
+		next(ctx)
+	}
+}
+
+func (c *Context) bodyTooLarge() {
+	if !c.writermem.Written() {
+		c.W.Header().Set("Connection", "close")
+		c.W.WriteHeader(http.StatusRequestEntityTooLarge)
+	}
+}
+
+//限制读取的字节数，限制按读取时ctx上的值算，超过时返回ErrBodyTooLarge并响应413
+type limitedBody struct {
+	ctx      *Context
+	rc       io.ReadCloser
+	n        int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	limit := b.ctx.maxBodySize
+	if limit <= 0 {
+		return b.rc.Read(p)
+	}
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	//外层已经读过一部分，里层又把限制调小了
+	if b.n > limit {
+		b.exceeded = true
+		b.ctx.bodyTooLarge()
+		return 0, ErrBodyTooLarge
+	}
+	//多读一个字节才能知道是不是超了
+	if remaining := limit - b.n + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	if b.n > limit {
+		n -= int(b.n - limit)
+		b.n = limit
+		b.exceeded = true
+		b.ctx.bodyTooLarge()
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+//去掉路径和控制字符，只保留文件名本身
+func SanitizeFilename(name string) string {
+	//windows客户端传上来的可能是 C:\xx\a.png
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if len(name) > maxFilenameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:maxFilenameLength-len(ext)], "") + ext
+	}
+	if name == "" {
+		name = "file"
+	}
+	return name
+}
+
+//按文件内容(前512字节)判断MIME类型，不相信客户端传的Content-Type
+func DetectContentType(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+//把上传的文件保存到dir下，文件名做了清理，重名时加序号，不会覆盖已有文件
+//allowedTypes为空时不检查类型，返回保存后的路径
+func (c *Context) SaveUploadedFileTo(file *multipart.FileHeader, dir string, allowedTypes ...string) (string, error) {
+	if len(allowedTypes) > 0 {
+		contentType, err := DetectContentType(file)
+		if err != nil {
+			return "", err
+		}
+		if !storage.MatchType(contentType, allowedTypes) {
+			return "", fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, contentType)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := SanitizeFilename(file.Filename)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	for i := 0; i < 100; i++ {
+		dst := filepath.Join(dir, name)
+		out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			name = fmt.Sprintf("%s_%d%s", base, i+1, ext)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(out, src)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(dst)
+			return "", err
+		}
+		return dst, nil
+	}
+	return "", fmt.Errorf("zjcgo: too many files named %s", file.Filename)
+}
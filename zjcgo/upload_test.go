@@ -0,0 +1,167 @@
+package zjcgo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	engine := New()
+	engine.MaxBodySize = 1 << 20
+	g := engine.Group("api")
+	calls := 0
+	var readErr error
+	g.Post("/upload", func(ctx *Context) {
+		calls++
+		_, readErr = io.ReadAll(ctx.R.Body)
+		if readErr == nil {
+			ctx.String(http.StatusOK, "ok")
+		}
+	}, BodyLimit(10))
+
+	//Content-Length已经超了，不进处理函数
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(strings.Repeat("x", 100))))
+	if w.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+		t.Fatalf("declared length: %d, calls %d", w.Code, calls)
+	}
+
+	//不知道长度的按读到的字节算
+	r := httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(strings.Repeat("x", 100)))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge || !errors.Is(readErr, ErrBodyTooLarge) {
+		t.Fatalf("chunked body: %d, err %v", w.Code, readErr)
+	}
+
+	//刚好等于限制的可以通过
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(strings.Repeat("x", 10))))
+	if w.Code != http.StatusOK || readErr != nil {
+		t.Fatalf("body at limit: %d, err %v", w.Code, readErr)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := map[string]string{
+		"a.png":                  "a.png",
+		"../../etc/passwd":       "passwd",
+		`..\..\windows\win.ini`:  "win.ini",
+		`C:\Users\zjc\photo.jpg`: "photo.jpg",
+		"/":                      "file",
+		"..":                     "file",
+		".htaccess":              "htaccess",
+		"a\x00b\n.txt":           "ab.txt",
+		`x<y>:"|?*.txt`:          "xy.txt",
+		" ../ ":                  "file",
+	}
+	for in, want := range cases {
+		if got := SanitizeFilename(in); got != want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+	//太长的截断，保留扩展名
+	long := SanitizeFilename(strings.Repeat("a", 300) + ".png")
+	if len(long) != maxFilenameLength || !strings.HasSuffix(long, ".png") {
+		t.Errorf("long filename %d bytes: %q", len(long), long)
+	}
+}
+
+//造一个multipart请求，取出里面的文件
+func uploadedFile(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return r.MultipartForm.File["file"][0]
+}
+
+func TestSaveUploadedFileTo(t *testing.T) {
+	dir := t.TempDir()
+	ctx := &Context{}
+	var paths []string
+	for _, content := range []string{"first", "second", "third"} {
+		path, err := ctx.SaveUploadedFileTo(uploadedFile(t, "a.txt", []byte(content)), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	//重名时加序号，已有的文件不会被覆盖
+	for i, name := range []string{"a.txt", "a_1.txt", "a_2.txt"} {
+		if paths[i] != filepath.Join(dir, name) {
+			t.Fatalf("path %d = %s, want %s", i, paths[i], name)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if string(data) != "first" {
+		t.Fatalf("existing file overwritten: %q", data)
+	}
+
+	//带路径的文件名只能落在dir下面
+	//multipart解析时会去掉路径，这里直接改成客户端原样传上来的
+	file := uploadedFile(t, "evil.txt", []byte("x"))
+	file.Filename = "../../evil.txt"
+	sub := filepath.Join(dir, "sub")
+	path, err := ctx.SaveUploadedFileTo(file, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(sub, "evil.txt") {
+		t.Fatalf("traversal filename saved to %s", path)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+		t.Fatal("file escaped the upload dir")
+	}
+
+	//按内容判断类型，不看扩展名
+	_, err = ctx.SaveUploadedFileTo(uploadedFile(t, "fake.png", []byte("<html><script>alert(1)</script>")), dir, "image/*")
+	if !errors.Is(err, ErrFileTypeNotAllowed) {
+		t.Fatalf("want ErrFileTypeNotAllowed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fake.png")); !os.IsNotExist(err) {
+		t.Fatal("rejected file should not be saved")
+	}
+}
+
+//外层中间件先读了body，里层的BodyLimit把限制调得比已读的还小
+func TestBodyLimitLoweredAfterRead(t *testing.T) {
+	engine := New()
+	engine.MaxBodySize = 1 << 20
+	g := engine.Group("api")
+	var readErr error
+	g.Post("/upload", func(ctx *Context) {
+		_, readErr = io.ReadAll(ctx.R.Body)
+	}, BodyLimit(10), func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			buf := make([]byte, 50)
+			io.ReadFull(ctx.R.Body, buf)
+			next(ctx)
+		}
+	})
+	r := httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(strings.Repeat("x", 100)))
+	r.ContentLength = -1
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge || !errors.Is(readErr, ErrBodyTooLarge) {
+		t.Fatalf("status %d, err %v", w.Code, readErr)
+	}
+}
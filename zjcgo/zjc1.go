@@ -5,10 +5,13 @@ import (
 	"github.com/zhengjingcheng/zjcgo/config"
 	zjcLog "github.com/zhengjingcheng/zjcgo/log"
 	"github.com/zhengjingcheng/zjcgo/render"
+	"github.com/zhengjingcheng/zjcgo/securecookie"
 	"html/template"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 /*
@@ -60,6 +63,10 @@ func (r *router) Use(middlewareFunc ...MiddlewareFunc) {
 }
 
 func (r *router) methodHandle(name string, method string, h HandlerFunc, ctx *Context) {
+	//处理函数里ctx.Error收集的错误
+	h = renderErrors(h)
+	//再检查Content-Length，这时路由级别的BodyLimit已经生效
+	h = checkBodySize(h)
 	//组通用中间件
 	if r.middlewares != nil {
 		for _, midwareFunc := range r.middlewares {
@@ -122,10 +129,65 @@ func (r *router) Head(name string, handle HandlerFunc, middlewareFunc ...Middlew
 	r.Handler(name, http.MethodHead, handle, middlewareFunc...)
 }
 
+//挂载标准库的http.Handler
+func (r *router) Handle(method string, name string, handler http.Handler, middlewareFunc ...MiddlewareFunc) {
+	r.Handler(name, method, WrapH(handler), middlewareFunc...)
+}
+
+//把http.Handler挂到prefix下面，handler看到的路径去掉了组名和prefix
+//访问prefix本身会重定向到prefix/
+func (r *router) Mount(prefix string, handler http.Handler, middlewareFunc ...MiddlewareFunc) {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	fullPrefix := prefix
+	if r.groupName != "" {
+		fullPrefix = "/" + r.groupName + prefix
+	}
+	if prefix != "" {
+		r.Any(prefix, func(ctx *Context) {
+			u := *ctx.R.URL
+			u.Path += "/"
+			http.Redirect(ctx.W, ctx.R, u.String(), http.StatusMovedPermanently)
+		}, middlewareFunc...)
+	}
+	r.Any(prefix+"/**", func(ctx *Context) {
+		path := ctx.R.URL.Path
+		index := strings.Index(path, fullPrefix)
+		if index < 0 {
+			handler.ServeHTTP(ctx.W, ctx.R)
+			return
+		}
+		req := new(http.Request)
+		*req = *ctx.R
+		u := *ctx.R.URL
+		u.Path = path[index+len(fullPrefix):]
+		u.RawPath = ""
+		req.URL = &u
+		handler.ServeHTTP(ctx.W, req)
+	}, middlewareFunc...)
+}
+
+//http.Handler转成HandlerFunc
+func WrapH(handler http.Handler) HandlerFunc {
+	return func(ctx *Context) {
+		handler.ServeHTTP(ctx.W, ctx.R)
+	}
+}
+
+//http.HandlerFunc转成HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		f(ctx.W, ctx.R)
+	}
+}
+
 /*
 ··························································封装服务器引擎·················································
 */
 
+//返回状态码和要渲染成json的内容，没注册时返回application/problem+json
 type ErrorHandler func(err error) (int, any)
 
 //路由服务引擎(封装一个路由组)
@@ -133,18 +195,33 @@ type Engine struct {
 	routerGroup                    //路由组，必须品
 	funcMap      template.FuncMap  //加载模板的句柄函数
 	HTMLRender   render.HTMLRender //HTML渲染函数
+	templates    *contextTemplates //注册了ContextFunc时按请求绑定Context的模板副本
 	pool         sync.Pool         //加载上下文切换内容
 	Logger       *zjcLog.Logger
 	middles      []MiddlewareFunc
 	errorHandler ErrorHandler
+	//签名和加密cookie用的key
+	cookieSigner    *securecookie.Signer
+	cookieEncrypter *securecookie.Encrypter
+	cookieMaxAge    time.Duration
+	//请求体最大字节数，<=0不限制，单个路由可以用BodyLimit中间件覆盖
+	MaxBodySize int64
+	//解析multipart表单时放在内存里的最大字节数
+	MaxMultipartMemory int64
+	//Run启动的server和Shutdown时要执行的函数
+	serverMu      sync.Mutex
+	server        *http.Server
+	shutdownHooks []func()
 }
 
 //初始化
 func New() *Engine {
 	//初始化路由组
 	engine := &Engine{
-		routerGroup: routerGroup{},
+		routerGroup:        routerGroup{},
+		MaxMultipartMemory: defaultMultipartMemory,
 	}
+	engine.routerGroup.engine = engine
 	engine.pool.New = func() any {
 		return engine.allocateContext() //后续可能增加很多的属性
 	}
@@ -156,11 +233,54 @@ func Default() *Engine {
 	engine := New()
 	engine.routerGroup.engine = engine
 	engine.Logger = zjcLog.Default()
-	logPath, ok := config.Conf.Log["path"]
+	logConf := config.Section("log")
+	logPath, ok := logConf["path"]
 	if ok {
+		//[log] max_size=100(M) rotate="daily" max_backups=7 max_age=30(天) compress=true
+		engine.Logger.LogFileSize = config.Int(logConf, "max_size", 100) << 20
+		rotation, err := zjcLog.ParseRotation(config.String(logConf, "rotate", ""))
+		if err != nil {
+			engine.Logger.Error(err.Error())
+		}
+		engine.Logger.Rotate = zjcLog.RotateConfig{
+			Rotation:   rotation,
+			MaxBackups: int(config.Int(logConf, "max_backups", 0)),
+			MaxAge:     time.Duration(config.Int(logConf, "max_age", 0)) * 24 * time.Hour,
+			Compress:   config.Bool(logConf, "compress", false),
+		}
 		engine.Logger.SetLogPath(logPath.(string))
 	}
-	engine.Use(Logging, Recovery) //调用打印日志中间件(通用)
+	//[log] level="info"，配置重新加载以后跟着变，配置里去掉的恢复原来的级别
+	levels := newConfigLevels(engine.Logger)
+	levels.apply(logConf)
+	config.OnReload(func(conf *config.ZjcConfig) {
+		levels.apply(config.Section("log"))
+	})
+	//[log] format="json" 可选text、json、logfmt
+	if format, ok := logConf["format"].(string); ok {
+		formatter, err := zjcLog.NewFormatter(format)
+		if err != nil {
+			engine.Logger.Error(err.Error())
+		} else {
+			engine.Logger.Formatter = formatter
+		}
+	}
+	//[log] async=true buffer_size=1024 overflow="drop-oldest" 文件日志异步写
+	if config.Bool(logConf, "async", false) {
+		overflow, err := zjcLog.ParseOverflow(config.String(logConf, "overflow", ""))
+		if err != nil {
+			engine.Logger.Error(err.Error())
+		}
+		engine.Logger.SetAsync(zjcLog.AsyncConfig{
+			BufferSize: int(config.Int(logConf, "buffer_size", 1024)),
+			Overflow:   overflow,
+		})
+	}
+	//[log.sampling] first=100 thereafter=100 interval="1s"，[log.sampling.error]单独给error级别配置
+	if sampling, ok := logConf["sampling"].(map[string]any); ok {
+		engine.Logger.SetSampling(samplingConfig(engine.Logger, sampling))
+	}
+	engine.Use(AccessLog(accessLogConfig(engine.Logger)), Recovery) //调用打印日志中间件(通用)
 	return engine
 }
 
@@ -173,16 +293,27 @@ func (e *Engine) allocateContext() any {
 */
 
 //渲染html的三个接口
+//多次调用会合并，同名的以后面的为准，要在LoadTemplate之前调用
+//值是ContextFunc的，执行时拿到当前请求的Context
 func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
-	e.funcMap = funcMap
+	if e.funcMap == nil {
+		e.funcMap = make(template.FuncMap, len(funcMap))
+	}
+	for name, fn := range funcMap {
+		e.funcMap[name] = fn
+	}
 }
 
 func (e *Engine) LoadTemplate(pattern string) {
-	t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
+	t := template.Must(template.New("").Funcs(e.parseFuncMap()).ParseGlob(pattern))
 	e.SetHtmlTemplate(t)
 }
 func (e *Engine) SetHtmlTemplate(t *template.Template) {
 	e.HTMLRender = render.HTMLRender{Template: t}
+	e.templates = nil
+	if funcs := e.contextFuncs(); len(funcs) > 0 {
+		e.templates = newContextTemplates(t, funcs)
+	}
 }
 
 /*
@@ -196,9 +327,14 @@ func (e *Engine) httpRequestHandle(ctx *Context, w http.ResponseWriter, r *http.
 		//遍历每一个路由
 		//不能使用r.RequestURI需要使用
 		routerName := SubstringLast(r.URL.Path, "/"+g.groupName)
+		if routerName == "" && r.URL.Path == "/"+g.groupName {
+			//访问的就是路由组本身 比如 /metrics
+			routerName = "/"
+		}
 		node := g.treeNode.Get(routerName)
 		if node != nil && node.isEnd {
 			//如果路由匹配上了
+			ctx.fullPath = "/" + g.groupName + node.routerName
 			handler, ok := g.handlerMap[node.routerName][ANY]
 			if ok {
 				g.methodHandle(node.routerName, ANY, handler, ctx)
@@ -229,25 +365,28 @@ func (e *Engine) httpRequestHandle(ctx *Context, w http.ResponseWriter, r *http.
 //实现serverhttp 则说明也可以作为一个handler
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := e.pool.Get().(*Context)
-	ctx.W = w
-	ctx.R = r
-	ctx.Logger = e.Logger
+	ctx.reset(w, r)
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &limitedBody{ctx: ctx, rc: r.Body}
+	}
 	e.httpRequestHandle(ctx, w, r)
+	//中间件里收集的错误
+	ctx.flushErrors()
 
 	e.pool.Put(ctx)
 }
 func (e *Engine) Run(addr string) {
-	http.Handle("/", e)
-	err := http.ListenAndServe(addr, nil)
-	if err != nil {
+	//不走http.DefaultServeMux，免得别的包注册的handler(比如net/http/pprof)绕过路由和中间件暴露出去
+	err := e.newServer(addr).ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 }
 
 //添加https支持
 func (e *Engine) RUNTLS(addr, certFile, keyFile string) {
-	err := http.ListenAndServeTLS(addr, certFile, keyFile, e.Handler())
-	if err != nil {
+	err := e.newServer(addr).ListenAndServeTLS(certFile, keyFile)
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 }
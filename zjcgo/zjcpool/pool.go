@@ -47,11 +47,12 @@ func NewPool(cap int32) (*Pool, error) {
 	return NewTimePool(cap, DefaultExpire)
 }
 func NewPoolConfig() (*Pool, error) {
-	cap, ok := config.Conf.Pool["cap"]
-	if !ok {
+	section := config.Section("pool")
+	if _, ok := section["cap"]; !ok {
 		return nil, errors.New("cap config not exist")
 	}
-	return NewPool(cap.(int32))
+	//toml里的整数解析出来是int64
+	return NewPool(int32(config.Int(section, "cap", 0)))
 }
 func NewTimePool(cap int32, expire int) (*Pool, error) {
 	if cap <= 0 {
@@ -225,6 +226,23 @@ func (p *Pool) Release() {
 	})
 }
 
+//正在运行的worker数量
+func (p *Pool) Running() int {
+	return int(atomic.LoadInt32(&p.running))
+}
+
+//空闲的worker数量
+func (p *Pool) Idle() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.workers)
+}
+
+//池的容量
+func (p *Pool) Cap() int {
+	return int(p.cap)
+}
+
 //判断是不是已经关闭了
 func (p *Pool) IsClosed() bool {
 	return len(p.release) > 0